@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"blog-backend/config"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrMiss is returned by Get when the key is not cached.
+var ErrMiss = errors.New("Cache miss.")
+
+// Cache stores values by key for a limited time.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value, a zero ttl keeps it until it is evicted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Add stores the value only if the key is not cached, and reports whether
+	// it did, atomically.
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// NewFromConfig creates the cache selected by CACHE_DRIVER, memory is the default.
+func NewFromConfig(cfg *config.Config) (Cache, error) {
+	switch cfg.CacheDriver {
+	case "", "memory":
+		log.Printf("Using in-process cache of %d entries", cfg.CacheSize)
+		return NewLRU(int(cfg.CacheSize)), nil
+	case "redis":
+		log.Printf("Using Redis cache at %s", cfg.RedisAddr)
+		return NewRedis(cfg.RedisAddr, cfg.RedisPassword, int(cfg.RedisDB)), nil
+	case "none":
+		return Nop{}, nil
+	default:
+		return nil, fmt.Errorf("Unknown cache driver %q.", cfg.CacheDriver)
+	}
+}
+
+// Nop caches nothing, every Get misses.
+type Nop struct{}
+
+func (Nop) Get(ctx context.Context, key string) ([]byte, error) { return nil, ErrMiss }
+
+func (Nop) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error { return nil }
+
+func (Nop) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (Nop) Delete(ctx context.Context, keys ...string) error { return nil }
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Store reads through a Cache, keeping values as JSON. Concurrent misses of a
+// key share one load, so an expired hot key does not stampede the database.
+// Cache failures are logged and fall back to loading. A nil *Store always loads.
+type Store struct {
+	Cache Cache
+	TTL   time.Duration
+
+	group singleflight.Group
+}
+
+// NewStore caches loaded values in c for ttl.
+func NewStore(c Cache, ttl time.Duration) *Store {
+	return &Store{Cache: c, TTL: ttl}
+}
+
+// Fetch returns the cached value of key, or loads and caches it. Values go
+// through JSON, so fields hidden from JSON are not set on cached values.
+func Fetch[T any](ctx context.Context, s *Store, key string, load func() (T, error)) (T, error) {
+	var value T
+	if s == nil {
+		return load()
+	}
+
+	data, err := s.Cache.Get(ctx, key)
+	if err == nil {
+		if err := json.Unmarshal(data, &value); err == nil {
+			return value, nil
+		}
+	} else if !errors.Is(err, ErrMiss) {
+		log.Printf("Cache get %s failed: %v", key, err)
+	}
+
+	shared, err, _ := s.group.Do(key, func() (any, error) {
+		loaded, err := load()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(loaded)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Cache.Set(ctx, key, data, s.TTL); err != nil {
+			log.Printf("Cache set %s failed: %v", key, err)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return value, err
+	}
+
+	// every caller decodes its own copy, so callers may modify it
+	err = json.Unmarshal(shared.([]byte), &value)
+	return value, err
+}
+
+// Key builds the key of parts within the namespace. Keys carry the namespace's
+// current generation, so Invalidate drops all keys of a namespace at once and
+// the old entries simply expire.
+func (s *Store) Key(ctx context.Context, namespace string, parts ...any) string {
+	if s == nil {
+		return ""
+	}
+
+	var key strings.Builder
+	key.WriteString(namespace + ":" + s.generation(ctx, namespace))
+	for _, part := range parts {
+		fmt.Fprintf(&key, ":%v", part)
+	}
+	return key.String()
+}
+
+// Invalidate drops every cached value of the namespaces.
+func (s *Store) Invalidate(ctx context.Context, namespaces ...string) {
+	if s == nil {
+		return
+	}
+	for _, namespace := range namespaces {
+		if err := s.Cache.Set(ctx, "gen:"+namespace, newGeneration(), 0); err != nil {
+			log.Printf("Cache invalidate %s failed: %v", namespace, err)
+		}
+	}
+}
+
+func (s *Store) generation(ctx context.Context, namespace string) string {
+	gen, err := s.Cache.Get(ctx, "gen:"+namespace)
+	if err == nil {
+		return string(gen)
+	}
+	if !errors.Is(err, ErrMiss) {
+		// the value will not be cached either
+		return "0"
+	}
+
+	gen = newGeneration()
+	if err := s.Cache.Set(ctx, "gen:"+namespace, gen, 0); err != nil {
+		log.Printf("Cache set gen:%s failed: %v", namespace, err)
+	}
+	return string(gen)
+}
+
+func newGeneration() []byte {
+	return []byte(strconv.FormatInt(time.Now().UnixNano(), 36))
+}
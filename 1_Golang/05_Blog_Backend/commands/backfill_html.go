@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"blog-backend/models"
+	"blog-backend/utils"
+	"flag"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// backfillHTML renders the content_html of posts and comments saved before it
+// existed. Reads do not render it, such rows show no HTML until this is run.
+func backfillHTML(env *Env, args []string) error {
+	flags := flag.NewFlagSet("backfill-html", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only count posts and comments without HTML")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	posts := env.DB.Unscoped().Model(&models.Post{}).Where("(content_html IS NULL OR content_html = '') AND content <> ''")
+	comments := env.DB.Unscoped().Model(&models.Comment{}).Where("(content_html IS NULL OR content_html = '') AND content <> ''")
+	if *dryRun {
+		var postCount, commentCount int64
+		if err := posts.Count(&postCount).Error; err != nil {
+			return err
+		}
+		if err := comments.Count(&commentCount).Error; err != nil {
+			return err
+		}
+		fmt.Printf("%d posts and %d comments without HTML, run without -dry-run to render them.\n", postCount, commentCount)
+		return nil
+	}
+
+	// the columns are updated alone, so updated_at and the hooks are left alone
+	renderedPosts := 0
+	var postBatch []models.Post
+	err := posts.Select("id", "content", "content_format").Order("id").FindInBatches(&postBatch, 100, func(batch *gorm.DB, _ int) error {
+		for _, post := range postBatch {
+			html := utils.RenderContent(post.Content, post.ContentFormat, utils.PostPolicy)
+			if err := env.DB.Unscoped().Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumn("content_html", html).Error; err != nil {
+				return fmt.Errorf("post %d: %w", post.ID, err)
+			}
+			renderedPosts++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	renderedComments := 0
+	var commentBatch []models.Comment
+	err = comments.Select("id", "content").Order("id").FindInBatches(&commentBatch, 100, func(batch *gorm.DB, _ int) error {
+		for _, comment := range commentBatch {
+			html := utils.RenderContent(comment.Content, utils.FormatMarkdown, utils.CommentPolicy)
+			if err := env.DB.Unscoped().Model(&models.Comment{}).Where("id = ?", comment.ID).UpdateColumn("content_html", html).Error; err != nil {
+				return fmt.Errorf("comment %d: %w", comment.ID, err)
+			}
+			renderedComments++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d posts and %d comments rendered.\n", renderedPosts, renderedComments)
+	return nil
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"blog-backend/models"
+	"flag"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// backfillSlugs assigns slugs to posts created before slugs existed.
+func backfillSlugs(env *Env, args []string) error {
+	flags := flag.NewFlagSet("backfill-slugs", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only count posts without a slug")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query := env.DB.Unscoped().Model(&models.Post{}).Where("slug IS NULL OR slug = ''")
+	if *dryRun {
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
+		fmt.Printf("%d posts without a slug, run without -dry-run to assign them.\n", count)
+		return nil
+	}
+
+	authorScoped := env.Config.SlugScope == "author"
+	assigned := 0
+	var posts []models.Post
+	err := query.Order("id").FindInBatches(&posts, 100, func(batch *gorm.DB, _ int) error {
+		for i := range posts {
+			if err := env.DB.Transaction(func(tx *gorm.DB) error {
+				return models.AssignSlug(tx, &posts[i], authorScoped)
+			}); err != nil {
+				return fmt.Errorf("post %d: %w", posts[i].ID, err)
+			}
+			assigned++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d slugs assigned.\n", assigned)
+	return nil
+}
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"blog-backend/config"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Env is what maintenance commands can use.
+type Env struct {
+	DB     *gorm.DB
+	Config *config.Config
+}
+
+// command runs with the arguments following its name.
+type command func(env *Env, args []string) error
+
+var commands = map[string]command{
+	"backfill-html":  backfillHTML,
+	"backfill-slugs": backfillSlugs,
+	"export":         exportSite,
+	"import":         importPosts,
+	"recount":        recount,
+}
+
+// Run executes the maintenance command named by args[0], e.g. `go run main.go recount`.
+func Run(env *Env, args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown command %q, available: %v", args[0], names)
+	}
+	return cmd(env, args[1:])
+}
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"blog-backend/services"
+	"flag"
+	"fmt"
+)
+
+// exportSite renders the published posts, author, tag and index pages, the
+// feeds and the sitemap to a directory of static files.
+func exportSite(env *Env, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := flags.String("out", "public", "output directory, replaced by each export")
+	templates := flags.String("templates", "", "directory with list.html, post.html and layout.html, the built-in templates if empty")
+	pageSize := flags.Int("page-size", 20, "posts per index page")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	tmpl, err := services.LoadSiteTemplates(*templates)
+	if err != nil {
+		return err
+	}
+
+	site := &services.StaticExport{
+		Posts:     &services.PostReader{DB: env.DB},
+		SiteURL:   env.Config.SiteURL,
+		SiteTitle: env.Config.SiteTitle,
+		PageSize:  *pageSize,
+		Templates: tmpl,
+	}
+	result, err := site.Export(*out)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d posts, %d authors and %d tags to %s (%d files).\n",
+		result.Posts, result.Authors, result.Tags, *out, result.Files)
+	return nil
+}
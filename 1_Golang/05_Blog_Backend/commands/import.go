@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"blog-backend/services"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// importExtensions are the files picked up from directories; files named on
+// the command line are imported whatever their extension, and fail if it is
+// not supported.
+var importExtensions = map[string]bool{".md": true, ".markdown": true, ".xml": true, ".json": true, ".zip": true}
+
+// importPosts creates posts from Markdown files, WordPress exports and data
+// exports, e.g. `go run main.go import -author admin -map jane=jdoe old-blog/`.
+func importPosts(env *Env, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only report what would be imported")
+	author := flags.String("author", "", "username of the author of posts whose own author is not found")
+	mapping := flags.String("map", "", "comma separated source=username pairs mapping authors of the source to users")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("no files given, usage: import [-dry-run] [-author username] [-map source=username,...] files or directories")
+	}
+
+	authors := map[string]string{}
+	if *mapping != "" {
+		for _, pair := range strings.Split(*mapping, ",") {
+			source, username, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(username) == "" {
+				return fmt.Errorf("invalid author mapping %q, expected source=username", pair)
+			}
+			authors[strings.TrimSpace(source)] = strings.TrimSpace(username)
+		}
+	}
+
+	var files []services.ImportFile
+	for _, root := range flags.Args() {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || (path != root && !importExtensions[strings.ToLower(filepath.Ext(path))]) {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files = append(files, services.ImportFile{Name: path, Data: data})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	importer := &services.Importer{
+		DB:                env.DB,
+		AuthorScopedSlugs: env.Config.SlugScope == "author",
+		Authors:           authors,
+		DefaultAuthor:     *author,
+		DryRun:            *dryRun,
+	}
+	report, err := importer.Import(files)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range report.Items {
+		switch item.Status {
+		case services.ImportCreated:
+			fmt.Printf("created  %s: %q as %s\n", item.Source, item.Title, item.Slug)
+		case services.ImportSkipped:
+			fmt.Printf("skipped  %s: %q, %s\n", item.Source, item.Title, item.Reason)
+		default:
+			fmt.Printf("failed   %s: %q, %s\n", item.Source, item.Title, item.Error)
+		}
+	}
+
+	if *dryRun {
+		fmt.Printf("%d posts would be created, %d skipped, %d failed; run without -dry-run to import them.\n",
+			report.Created, report.Skipped, report.Failed)
+	} else {
+		fmt.Printf("%d posts created, %d skipped, %d failed.\n", report.Created, report.Skipped, report.Failed)
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d items failed", report.Failed)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"blog-backend/services"
+	"context"
+	"flag"
+	"fmt"
+)
+
+// recount reports counter drift and fixes it unless -dry-run is given.
+func recount(env *Env, args []string) error {
+	flags := flag.NewFlagSet("recount", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only report mismatches")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	mismatches, err := services.Recount(context.Background(), env.DB, !*dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range mismatches {
+		fmt.Printf("%s[%s]: stored %d, actual %d\n", m.Counter, m.Key, m.Stored, m.Actual)
+	}
+
+	switch {
+	case len(mismatches) == 0:
+		fmt.Println("All counters are consistent.")
+	case *dryRun:
+		fmt.Printf("%d mismatches found, run without -dry-run to fix them.\n", len(mismatches))
+	default:
+		fmt.Printf("%d mismatches fixed.\n", len(mismatches))
+	}
+	return nil
+}
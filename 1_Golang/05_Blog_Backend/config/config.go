@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +18,61 @@ type Config struct {
 	DBPort     string
 	DBName     string
 	ServerPort string
+
+	// SiteURL is the public base URL used for absolute links in feeds, without trailing slash.
+	SiteURL   string
+	SiteTitle string
+
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+
+	StorageDriver   string
+	StorageLocalDir string
+	S3Endpoint      string
+	S3Region        string
+	S3Bucket        string
+	S3AccessKey     string
+	S3SecretKey     string
+	MaxImageBytes   int64
+	MaxFileBytes    int64
+
+	WebhookAllowPrivate bool
+
+	JobWorkers           int
+	JobVisibilityTimeout int64
+	RecountInterval      int64
+
+	TrashRetentionDays int64
+
+	ReportHideThreshold int64
+
+	BannedWords     []string
+	MaxLinks        int64
+	NewAccountHours int64
+
+	CacheDriver   string
+	CacheSize     int64
+	CacheTTL      int64
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int64
+
+	// ViewDedupMinutes is how long repeated views of a post by one user or IP count once.
+	ViewDedupMinutes  int64
+	ViewFlushInterval int64
+	// TrustedProxies are the addresses or CIDRs of reverse proxies whose
+	// X-Forwarded-For header tells the client IP, none by default.
+	TrustedProxies []string
+
+	// SlugScope is "global" (default) or "author" for per-author unique slugs.
+	SlugScope string
+}
+
+// OIDCEnabled reports whether an OIDC provider has been configured.
+func (c *Config) OIDCEnabled() bool {
+	return c.OIDCIssuer != "" && c.OIDCClientID != "" && c.OIDCRedirectURL != ""
 }
 
 // GetDSN returns the DSN for the database.
@@ -44,13 +102,94 @@ func LoadConfig() *Config {
 		DBPort:     os.Getenv("DB_PORT"),
 		DBName:     os.Getenv("DB_NAME"),
 		ServerPort: os.Getenv("SERVER_PORT"),
+
+		SiteTitle: getEnv("SITE_TITLE", "Blog"),
+
+		OIDCIssuer:       os.Getenv("OIDC_ISSUER"),
+		OIDCClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+
+		StorageDriver:   os.Getenv("STORAGE_DRIVER"),
+		StorageLocalDir: getEnv("STORAGE_LOCAL_DIR", "uploads"),
+		S3Endpoint:      os.Getenv("S3_ENDPOINT"),
+		S3Region:        os.Getenv("S3_REGION"),
+		S3Bucket:        os.Getenv("S3_BUCKET"),
+		S3AccessKey:     os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:     os.Getenv("S3_SECRET_KEY"),
+		MaxImageBytes:   getEnvInt64("MAX_IMAGE_BYTES", 5<<20),
+		MaxFileBytes:    getEnvInt64("MAX_FILE_BYTES", 20<<20),
+
+		WebhookAllowPrivate: os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true",
+
+		JobWorkers:           int(getEnvInt64("JOB_WORKERS", 4)),
+		JobVisibilityTimeout: getEnvInt64("JOB_VISIBILITY_TIMEOUT", 300),
+		RecountInterval:      getEnvInt64("RECOUNT_INTERVAL", 3600),
+
+		TrashRetentionDays: getEnvInt64("TRASH_RETENTION_DAYS", 30),
+
+		ReportHideThreshold: getEnvInt64("REPORT_HIDE_THRESHOLD", 3),
+
+		BannedWords:     strings.Split(os.Getenv("CONTENT_BANNED_WORDS"), ","),
+		MaxLinks:        getEnvInt64("CONTENT_MAX_LINKS", 3),
+		NewAccountHours: getEnvInt64("NEW_ACCOUNT_HOURS", 24),
+
+		CacheDriver:   os.Getenv("CACHE_DRIVER"),
+		CacheSize:     getEnvInt64("CACHE_SIZE", 10000),
+		CacheTTL:      getEnvInt64("CACHE_TTL", 60),
+		RedisAddr:     getEnv("REDIS_ADDR", "localhost:6379"),
+		RedisPassword: os.Getenv("REDIS_PASSWORD"),
+		RedisDB:       getEnvInt64("REDIS_DB", 0),
+
+		ViewDedupMinutes:  getEnvInt64("VIEW_DEDUP_MINUTES", 30),
+		ViewFlushInterval: getEnvInt64("VIEW_FLUSH_INTERVAL", 10),
+
+		SlugScope: getEnv("SLUG_SCOPE", "global"),
 	}
 
-	log.Printf("Config load successfully:\nDBHost: %s\nDBPort: %s\nDBName: %s\nServerPort: %s\n",
+	if proxies := strings.TrimSpace(os.Getenv("TRUSTED_PROXIES")); proxies != "" {
+		for _, proxy := range strings.Split(proxies, ",") {
+			config.TrustedProxies = append(config.TrustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+
+	config.SiteURL = strings.TrimSuffix(getEnv("SITE_URL", "http://localhost"+config.ServerPort), "/")
+
+	log.Printf("Config load successfully:\nDBHost: %s\nDBPort: %s\nDBName: %s\nServerPort: %s\nOIDCIssuer: %s\n",
 		config.DBHost,
 		config.DBPort,
 		config.DBName,
-		config.ServerPort)
+		config.ServerPort,
+		config.OIDCIssuer)
 
 	return config
 }
+
+// TrashRetention is how long deleted posts and comments are kept before they
+// are purged, zero meaning forever.
+func (c *Config) TrashRetention() time.Duration {
+	return time.Duration(c.TrashRetentionDays) * 24 * time.Hour
+}
+
+// getEnv returns the environment variable or the fallback when it is unset.
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvInt64 returns the environment variable as an integer or the fallback when it is unset or invalid.
+func getEnvInt64(key string, fallback int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Warning: invalid %s %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
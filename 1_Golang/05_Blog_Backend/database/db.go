@@ -14,7 +14,7 @@ func InitDB(cfg *config.Config) *gorm.DB {
 	dsn := cfg.GetDSN()
 	db := connectDB(dsn)
 
-	db.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{})
+	db.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}, &models.Identity{}, &models.OAuthState{}, &models.APIToken{}, &models.AuditEvent{}, &models.PostRevision{}, &models.Attachment{}, &models.Reaction{}, &models.ReactionCount{}, &models.Bookmark{}, &models.Follow{}, &models.Notification{}, &models.NotificationPreference{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.Job{}, &models.PasswordReset{}, &models.Report{}, &models.PostSlug{}, &models.Tag{}, &models.PostDailyView{}, &models.DataExport{})
 
 	log.Println("Database initialized.")
 	return db
@@ -22,7 +22,10 @@ func InitDB(cfg *config.Config) *gorm.DB {
 
 // connectDB connects to the database and returns the database connection.
 func connectDB(dsn string) *gorm.DB {
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		// report unique index violations as gorm.ErrDuplicatedKey
+		TranslateError: true,
+	})
 
 	if err != nil {
 		log.Fatalf("Data base conncted failed: %v", err)
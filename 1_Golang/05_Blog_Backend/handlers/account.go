@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/storage"
+	"blog-backend/utils"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// erasureReauthWindow is how recent a sign-in must be to erase an account
+// without the password, for accounts that have none and sign in through OIDC.
+const erasureReauthWindow = 10 * time.Minute
+
+type AccountHandler struct {
+	DB       *gorm.DB
+	Storage  storage.Storage
+	Accounts *services.AccountService
+}
+
+type EraseAccountRequest struct {
+	Password string `json:"password"`
+	// Confirm must repeat the username.
+	Confirm string `json:"confirm" binding:"required"`
+}
+
+// GetDataExport handler for downloading an archive of the current user's data,
+// starting to build one when there is none that can be downloaded
+func (h *AccountHandler) GetDataExport(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var export models.DataExport
+	err := h.DB.Where("user_id = ?", userID).Order("id desc").First(&export).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch export")
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil && export.Downloadable(now):
+		h.serveExport(c, &export)
+	case err == nil && export.InProgress(now):
+		utils.Success(c, http.StatusAccepted, "Export is being prepared", export)
+	default:
+		started, err := h.Accounts.RequestExport(userID.(uint))
+		if err != nil {
+			utils.Error(c, http.StatusInternalServerError, "Failed to start export")
+			return
+		}
+		services.RecordAudit(h.DB, c, models.AuditUserDataExport, "user", userID.(uint), nil)
+		utils.Success(c, http.StatusAccepted, "Export is being prepared", started)
+	}
+}
+
+func (h *AccountHandler) serveExport(c *gin.Context, export *models.DataExport) {
+	reader, err := h.Storage.Get(c.Request.Context(), export.StorageKey)
+	if err != nil {
+		log.Printf("Reading data export %d failed: %v", export.ID, err)
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch export")
+		return
+	}
+	defer reader.Close()
+
+	name := fmt.Sprintf("blog-export-%s.zip", export.CreatedAt.Format("20060102-150405"))
+	c.DataFromReader(http.StatusOK, export.Size, "application/zip", reader, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": name}),
+		"Cache-Control":       "private, no-store",
+	})
+}
+
+// EraseAccount handler for permanently deleting the current user's account and
+// content, verified with the password or a sign-in within the last minutes
+func (h *AccountHandler) EraseAccount(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req EraseAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if req.Confirm != user.Username {
+		utils.Error(c, http.StatusBadRequest, "Confirm must be your username")
+		return
+	}
+
+	if req.Password != "" {
+		if !utils.CheckPassword(user.Password, req.Password) {
+			utils.Error(c, http.StatusForbidden, "Invalid password")
+			return
+		}
+	} else if user.Password != "" {
+		utils.Error(c, http.StatusForbidden, "Password is required")
+		return
+	} else if authTime, ok := c.Get("authTime"); !ok || time.Since(authTime.(time.Time)) > erasureReauthWindow {
+		utils.Error(c, http.StatusForbidden, "A sign-in within the last 10 minutes is required")
+		return
+	}
+
+	// recorded first, so the event is pseudonymized with the rest
+	services.RecordAudit(h.DB, c, models.AuditUserErase, "user", user.ID, nil)
+
+	if err := h.Accounts.RequestErasure(user.ID); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to erase account")
+		return
+	}
+
+	utils.Success(c, http.StatusAccepted, "Account is being erased", nil)
+}
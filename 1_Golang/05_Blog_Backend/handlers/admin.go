@@ -0,0 +1,632 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/utils"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AdminHandler struct {
+	DB       *gorm.DB
+	Webhooks *services.WebhookService
+	// AuthorScopedSlugs makes slugs of imported posts unique per author instead of site-wide.
+	AuthorScopedSlugs bool
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
+type ChangeStatusRequest struct {
+	Status string     `json:"status" binding:"required,oneof=active suspended banned"`
+	Reason string     `json:"reason" binding:"max=255"`
+	Until  *time.Time `json:"until"`
+}
+
+type BulkDeleteRequest struct {
+	IDs    []uint `json:"ids" binding:"max=500"`
+	UserID uint   `json:"user_id"`
+}
+
+// AdminUser is a user with the moderation state that only admins see.
+type AdminUser struct {
+	models.User
+	Email                 string     `json:"email"`
+	Status                string     `json:"status"`
+	StatusReason          string     `json:"status_reason,omitempty"`
+	StatusUntil           *time.Time `json:"status_until,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+}
+
+func newAdminUser(user models.User) AdminUser {
+	return AdminUser{
+		User:                  user,
+		Email:                 user.Email,
+		Status:                user.Status,
+		StatusReason:          user.StatusReason,
+		StatusUntil:           user.StatusUntil,
+		PasswordResetRequired: user.PasswordResetRequired,
+	}
+}
+
+// maxBulkDelete caps how many items one bulk removal touches.
+const maxBulkDelete = 500
+
+// maxImportBytes caps the size of the files of one import request.
+const maxImportBytes = 64 << 20
+
+// DailyStats is the number of sign-ups, posts and comments created on a day.
+type DailyStats struct {
+	Date     string `json:"date"`
+	Users    int64  `json:"users"`
+	Posts    int64  `json:"posts"`
+	Comments int64  `json:"comments"`
+}
+
+// ChangeUserRole handler for granting or revoking the admin role
+func (h *AdminHandler) ChangeUserRole(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, targetID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if user.ID == c.GetUint("userID") && req.Role != models.RoleAdmin {
+		utils.Error(c, http.StatusBadRequest, "Admins cannot revoke their own role")
+		return
+	}
+
+	changes := services.Changes{}.Add("role", user.Role, req.Role)
+
+	if err := h.DB.Model(&user).Update("role", req.Role).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to change role")
+		return
+	}
+
+	services.RecordAudit(h.DB, c, models.AuditUserRoleChange, "user", user.ID, changes)
+
+	utils.Success(c, 200, "Role changed successfully", newAdminUser(user))
+}
+
+// ListAuditEvents handler for querying the audit log by actor, action and time range
+func (h *AdminHandler) ListAuditEvents(c *gin.Context) {
+	query := h.DB.Model(&models.AuditEvent{})
+
+	if actorID := c.Query("actor_id"); actorID != "" {
+		id, err := strconv.ParseUint(actorID, 10, 64)
+		if err != nil {
+			utils.Error(c, http.StatusBadRequest, "Invalid actor ID")
+			return
+		}
+		query = query.Where("actor_id = ?", id)
+	}
+
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			utils.Error(c, http.StatusBadRequest, "Invalid from time, expected RFC3339")
+			return
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			utils.Error(c, http.StatusBadRequest, "Invalid to time, expected RFC3339")
+			return
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	page, pageSize := parsePagination(c)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch audit events")
+		return
+	}
+
+	var events []models.AuditEvent
+	if err := query.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch audit events")
+		return
+	}
+
+	utils.Success(c, 200, "Audit events fetched successfully", PagedResponse{
+		Items:      events,
+		Pagination: Pagination{Page: page, PageSize: pageSize, Total: total},
+	})
+}
+
+// ListJobs handler for inspecting the background job queue by status and type
+func (h *AdminHandler) ListJobs(c *gin.Context) {
+	query := h.DB.Model(&models.Job{})
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if jobType := c.Query("type"); jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+
+	page, pageSize := parsePagination(c)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch jobs")
+		return
+	}
+
+	var jobs []models.Job
+	if err := query.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&jobs).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch jobs")
+		return
+	}
+
+	var counts []struct {
+		Status string `json:"status"`
+		Total  int64  `json:"total"`
+	}
+	if err := h.DB.Model(&models.Job{}).Select("status, count(*) as total").Group("status").Scan(&counts).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch jobs")
+		return
+	}
+
+	utils.Success(c, 200, "Jobs fetched successfully", gin.H{
+		"counts":     counts,
+		"items":      jobs,
+		"pagination": Pagination{Page: page, PageSize: pageSize, Total: total},
+	})
+}
+
+// RetryJob handler for running a dead job again
+func (h *AdminHandler) RetryJob(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("job_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	var job models.Job
+	if err := h.DB.First(&job, jobID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	if job.Status != models.JobDead {
+		utils.Error(c, http.StatusConflict, "Only dead jobs can be retried")
+		return
+	}
+
+	if err := h.DB.Model(&job).Updates(map[string]any{
+		"status":      models.JobPending,
+		"attempts":    0,
+		"run_at":      time.Now(),
+		"finished_at": nil,
+	}).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to retry job")
+		return
+	}
+
+	h.DB.First(&job, job.ID)
+	utils.Success(c, 200, "Job queued successfully", job)
+}
+
+// SearchUsers handler for finding users by username or email, role and status
+func (h *AdminHandler) SearchUsers(c *gin.Context) {
+	query := h.DB.Model(&models.User{})
+
+	if q := c.Query("q"); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("username LIKE ? OR email LIKE ?", like, like)
+	}
+
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	page, pageSize := parsePagination(c)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+
+	var users []models.User
+	if err := query.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&users).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+
+	items := make([]AdminUser, len(users))
+	for i, user := range users {
+		items[i] = newAdminUser(user)
+	}
+	utils.Success(c, 200, "Users fetched successfully", PagedResponse{
+		Items:      items,
+		Pagination: Pagination{Page: page, PageSize: pageSize, Total: total},
+	})
+}
+
+// ChangeUserStatus handler for suspending, banning or reinstating a user
+func (h *AdminHandler) ChangeUserStatus(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req ChangeStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	if req.Status == models.UserSuspended && (req.Until == nil || !req.Until.After(time.Now())) {
+		utils.Error(c, http.StatusBadRequest, "Suspensions need an until time in the future")
+		return
+	}
+	if req.Status != models.UserSuspended {
+		req.Until = nil
+	}
+	if req.Status == models.UserActive {
+		req.Reason = ""
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, targetID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if user.ID == c.GetUint("userID") {
+		utils.Error(c, http.StatusBadRequest, "Admins cannot change their own status")
+		return
+	}
+	if user.Status == models.UserErased {
+		utils.Error(c, http.StatusConflict, "Account has been erased")
+		return
+	}
+
+	changes := services.Changes{}.
+		Add("status", user.Status, req.Status).
+		Add("reason", user.StatusReason, req.Reason).
+		Add("until", user.StatusUntil, req.Until)
+
+	if err := h.DB.Model(&user).Updates(map[string]any{
+		"status":        req.Status,
+		"status_reason": req.Reason,
+		"status_until":  req.Until,
+	}).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to change status")
+		return
+	}
+
+	services.RecordAudit(h.DB, c, models.AuditUserStatusChange, "user", user.ID, changes)
+
+	utils.Success(c, 200, "Status changed successfully", newAdminUser(user))
+}
+
+// ForcePasswordReset handler for invalidating a user's password, sessions and
+// tokens. The returned reset token is shown once and must reach the user out of band.
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, targetID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	token, err := utils.RandomString(32)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(models.PasswordResetTTL),
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.PasswordReset{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&reset).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.APIToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Updates(map[string]any{
+			"password_reset_required": true,
+			"sessions_valid_after":    time.Now(),
+		}).Error
+	})
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	services.RecordAudit(h.DB, c, models.AuditUserPasswordReset, "user", user.ID, nil)
+
+	utils.Success(c, 200, "Password reset successfully", gin.H{
+		"reset_token": token,
+		"expires_at":  reset.ExpiresAt,
+	})
+}
+
+// BulkDeletePosts handler for removing posts by ID or every post of a user
+func (h *AdminHandler) BulkDeletePosts(c *gin.Context) {
+	var req BulkDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (len(req.IDs) == 0 && req.UserID == 0) {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	query := h.DB.Limit(maxBulkDelete)
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.UserID != 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+
+	var posts []models.Post
+	if err := query.Find(&posts).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to delete posts")
+		return
+	}
+
+	moderatorID := c.GetUint("userID")
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range posts {
+			post := &posts[i]
+			if err := models.RemovePost(tx, post, moderatorID); err != nil {
+				return err
+			}
+			if err := enqueuePostDeleted(tx, h.Webhooks, post); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to delete posts")
+		return
+	}
+
+	for _, post := range posts {
+		services.RecordAudit(h.DB, c, models.AuditPostDelete, "post", post.ID, gin.H{"title": post.Title, "bulk": true})
+	}
+
+	utils.Success(c, 200, "Posts deleted successfully", gin.H{"deleted": len(posts)})
+}
+
+// BulkDeleteComments handler for removing comments by ID or every comment of a user
+func (h *AdminHandler) BulkDeleteComments(c *gin.Context) {
+	var req BulkDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (len(req.IDs) == 0 && req.UserID == 0) {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	query := h.DB.Limit(maxBulkDelete)
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.UserID != 0 {
+		query = query.Where("commenter_id = ?", req.UserID)
+	}
+
+	var comments []models.Comment
+	if err := query.Find(&comments).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to delete comments")
+		return
+	}
+
+	// deleted one by one so the hooks keep the comment counts right
+	moderatorID := c.GetUint("userID")
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range comments {
+			if err := models.RemoveComment(tx, &comments[i], moderatorID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to delete comments")
+		return
+	}
+
+	for _, comment := range comments {
+		services.RecordAudit(h.DB, c, models.AuditCommentDelete, "comment", comment.ID, gin.H{"content": comment.Content, "bulk": true})
+	}
+
+	utils.Success(c, 200, "Comments deleted successfully", gin.H{"deleted": len(comments)})
+}
+
+// GetStats handler for site-wide totals and daily sign-ups, posts and comments
+func (h *AdminHandler) GetStats(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		utils.Error(c, http.StatusBadRequest, "Invalid days, expected 1 to 365")
+		return
+	}
+
+	totals := map[string]int64{}
+	for name, model := range map[string]any{"users": &models.User{}, "posts": &models.Post{}, "comments": &models.Comment{}} {
+		var total int64
+		if err := h.DB.Model(model).Count(&total).Error; err != nil {
+			utils.Error(c, http.StatusInternalServerError, "Failed to fetch stats")
+			return
+		}
+		totals[name] = total
+	}
+	for _, status := range []string{models.UserSuspended, models.UserBanned} {
+		var total int64
+		if err := h.DB.Model(&models.User{}).Where("status = ?", status).Count(&total).Error; err != nil {
+			utils.Error(c, http.StatusInternalServerError, "Failed to fetch stats")
+			return
+		}
+		totals[status] = total
+	}
+
+	// one entry per day, oldest first, including days without activity
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := today.AddDate(0, 0, -(days - 1))
+	daily := make([]DailyStats, days)
+	index := make(map[string]*DailyStats, days)
+	for i := range daily {
+		daily[i].Date = since.AddDate(0, 0, i).Format(time.DateOnly)
+		index[daily[i].Date] = &daily[i]
+	}
+
+	for _, series := range []struct {
+		model any
+		field func(*DailyStats) *int64
+	}{
+		{&models.User{}, func(d *DailyStats) *int64 { return &d.Users }},
+		{&models.Post{}, func(d *DailyStats) *int64 { return &d.Posts }},
+		{&models.Comment{}, func(d *DailyStats) *int64 { return &d.Comments }},
+	} {
+		var rows []struct {
+			Day   time.Time
+			Total int64
+		}
+		if err := h.DB.Model(series.model).Select("DATE(created_at) AS day, COUNT(*) AS total").
+			Where("created_at >= ?", since).Group("DATE(created_at)").Scan(&rows).Error; err != nil {
+			utils.Error(c, http.StatusInternalServerError, "Failed to fetch stats")
+			return
+		}
+		for _, row := range rows {
+			if day, ok := index[row.Day.Format(time.DateOnly)]; ok {
+				*series.field(day) = row.Total
+			}
+		}
+	}
+
+	utils.Success(c, 200, "Stats fetched successfully", gin.H{
+		"totals": totals,
+		"daily":  daily,
+	})
+}
+
+// ImportPosts handler for creating posts from uploaded Markdown files,
+// WordPress exports and data exports
+func (h *AdminHandler) ImportPosts(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Files exceed the %d byte limit", maxImportBytes))
+			return
+		}
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	headers := form.File["files"]
+	if len(headers) == 0 {
+		utils.Error(c, http.StatusBadRequest, "files are required")
+		return
+	}
+
+	// authors of the source are mapped with repeated map=source=username fields
+	authors := map[string]string{}
+	for _, pair := range form.Value["map"] {
+		source, username, ok := strings.Cut(pair, "=")
+		if !ok || username == "" {
+			utils.Error(c, http.StatusBadRequest, "Invalid author mapping: "+pair)
+			return
+		}
+		authors[source] = username
+	}
+
+	files := make([]services.ImportFile, 0, len(headers))
+	for _, header := range headers {
+		file, err := header.Open()
+		if err != nil {
+			utils.Error(c, http.StatusBadRequest, "Failed to read file")
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			utils.Error(c, http.StatusBadRequest, "Failed to read file")
+			return
+		}
+		files = append(files, services.ImportFile{Name: filepath.Base(header.Filename), Data: data})
+	}
+
+	importer := &services.Importer{
+		DB:                h.DB,
+		AuthorScopedSlugs: h.AuthorScopedSlugs,
+		Authors:           authors,
+		DefaultAuthor:     c.Query("author"),
+		DryRun:            c.Query("dry_run") == "true",
+	}
+	report, err := importer.Import(files)
+	if errors.Is(err, services.ErrUnknownDefaultAuthor) {
+		utils.Error(c, http.StatusBadRequest, "Unknown default author")
+		return
+	}
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to import posts")
+		return
+	}
+
+	for _, item := range report.Items {
+		if item.Status == services.ImportCreated && item.PostID != 0 {
+			services.RecordAudit(h.DB, c, models.AuditPostImport, "post", item.PostID, gin.H{"source": item.Source})
+		}
+	}
+
+	if report.DryRun {
+		utils.Success(c, 200, "Import checked successfully", report)
+		return
+	}
+	utils.Success(c, 200, "Posts imported successfully", report)
+}
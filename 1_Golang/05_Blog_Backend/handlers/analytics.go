@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"blog-backend/services"
+	"blog-backend/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTrendingLimit = 10
+	maxTrendingLimit     = 50
+)
+
+type AnalyticsHandler struct {
+	DB    *gorm.DB
+	Posts *services.PostReader
+}
+
+// GetTrendingPosts handler for the posts with the most recent views, comments and reactions
+func (h *AnalyticsHandler) GetTrendingPosts(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultTrendingLimit)))
+	if err != nil || limit < 1 || limit > maxTrendingLimit {
+		utils.Error(c, http.StatusBadRequest, "Invalid limit, expected 1 to "+strconv.Itoa(maxTrendingLimit))
+		return
+	}
+
+	posts, err := h.Posts.CachedTrending(c.Request.Context(), limit)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch trending posts")
+		return
+	}
+
+	if err := withPostReactions(h.DB, posts); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch trending posts")
+		return
+	}
+
+	utils.Success(c, 200, "Trending posts fetched successfully", posts)
+}
+
+// GetMyAnalytics handler for the current user's daily views, comments and reactions on their posts
+func (h *AnalyticsHandler) GetMyAnalytics(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		utils.Error(c, http.StatusBadRequest, "Invalid days, expected 1 to 365")
+		return
+	}
+
+	analytics, err := services.LoadAuthorAnalytics(h.DB, userID.(uint), days)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch analytics")
+		return
+	}
+
+	utils.Success(c, 200, "Analytics fetched successfully", analytics)
+}
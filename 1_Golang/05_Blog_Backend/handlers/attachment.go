@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/storage"
+	"blog-backend/utils"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+var fileTypes = append([]string{
+	"application/pdf",
+	"application/zip",
+	"text/plain",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}, imageTypes...)
+
+// multipartOverhead is allowed on top of the file size for the multipart
+// boundaries and part headers of an upload.
+const multipartOverhead = 64 << 10
+
+type AttachmentHandler struct {
+	DB            *gorm.DB
+	Storage       storage.Storage
+	MaxImageBytes int64
+	MaxFileBytes  int64
+}
+
+// UploadImage handler for uploading an image to a post
+func (h *AttachmentHandler) UploadImage(c *gin.Context) {
+	h.upload(c, models.AttachmentImage, imageTypes, h.MaxImageBytes)
+}
+
+// UploadFile handler for uploading a file attachment to a post
+func (h *AttachmentHandler) UploadFile(c *gin.Context) {
+	h.upload(c, models.AttachmentFile, fileTypes, h.MaxFileBytes)
+}
+
+func (h *AttachmentHandler) upload(c *gin.Context, kind string, allowed []string, maxBytes int64) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var post models.Post
+	if err := h.DB.First(&post, postID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	if post.UserID != userID.(uint) {
+		utils.Error(c, http.StatusForbidden, "Only author can upload to this post")
+		return
+	}
+
+	// 1. read the file, refusing anything over the size limit before it is spooled to disk
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+multipartOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("File exceeds the %d byte limit", maxBytes))
+			return
+		}
+		utils.Error(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	if header.Size > maxBytes {
+		utils.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("File exceeds the %d byte limit", maxBytes))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		utils.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("File exceeds the %d byte limit", maxBytes))
+		return
+	}
+
+	// 2. sniff the content type, the client supplied one is not trusted
+	detected := mimetype.Detect(data)
+	if !isAllowedType(detected, allowed) {
+		utils.Error(c, http.StatusUnsupportedMediaType, "Unsupported file type: "+detected.String())
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(detected.String())
+
+	random, err := utils.RandomString(16)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to store file")
+		return
+	}
+
+	attachment := models.Attachment{
+		PostID:      post.ID,
+		UserID:      userID.(uint),
+		Kind:        kind,
+		FileName:    truncateName(filepath.Base(header.Filename)),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		StorageKey:  fmt.Sprintf("posts/%d/%s%s", post.ID, random, detected.Extension()),
+	}
+
+	// 3. images get their dimensions and a thumbnail when the format can be decoded
+	var thumbnail []byte
+	var thumbContentType string
+	if kind == models.AttachmentImage {
+		if _, w, h, err := utils.ImageConfig(data); err == nil {
+			attachment.Width, attachment.Height = w, h
+			thumbnail, thumbContentType, err = utils.Thumbnail(data)
+			if err != nil {
+				log.Printf("Thumbnail generation failed: %v", err)
+				thumbnail = nil
+			}
+		}
+	}
+
+	ctx := c.Request.Context()
+	if err := h.Storage.Put(ctx, attachment.StorageKey, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		log.Printf("Storing attachment failed: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "Failed to store file")
+		return
+	}
+
+	if thumbnail != nil {
+		key := fmt.Sprintf("posts/%d/%s_thumb%s", post.ID, random, thumbnailExtension(thumbContentType))
+		if err := h.Storage.Put(ctx, key, bytes.NewReader(thumbnail), int64(len(thumbnail)), thumbContentType); err != nil {
+			log.Printf("Storing thumbnail failed: %v", err)
+		} else {
+			attachment.ThumbnailKey = key
+		}
+	}
+
+	if err := h.DB.Create(&attachment).Error; err != nil {
+		h.Storage.Delete(ctx, attachment.StorageKey)
+		if attachment.ThumbnailKey != "" {
+			h.Storage.Delete(ctx, attachment.ThumbnailKey)
+		}
+		utils.Error(c, http.StatusInternalServerError, "Failed to save attachment")
+		return
+	}
+
+	utils.Success(c, 200, "Attachment uploaded successfully", attachment)
+}
+
+// ListAttachments handler for listing the attachments of a post
+func (h *AttachmentHandler) ListAttachments(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var post models.Post
+	if err := h.DB.Scopes(models.VisiblePosts).First(&post, postID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	var attachments []models.Attachment
+	if err := h.DB.Where("post_id = ?", post.ID).Find(&attachments).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch attachments")
+		return
+	}
+
+	utils.Success(c, 200, "Attachments fetched successfully", attachments)
+}
+
+// DownloadAttachment handler for streaming an attachment from storage
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	h.serve(c, false)
+}
+
+// DownloadThumbnail handler for streaming an image thumbnail from storage
+func (h *AttachmentHandler) DownloadThumbnail(c *gin.Context) {
+	h.serve(c, true)
+}
+
+func (h *AttachmentHandler) serve(c *gin.Context, thumbnail bool) {
+	attachmentID, err := strconv.ParseUint(c.Param("attachment_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid attachment ID")
+		return
+	}
+
+	var attachment models.Attachment
+	if err := h.DB.Joins("JOIN posts ON posts.id = attachments.post_id AND posts.deleted_at IS NULL").
+		Scopes(models.VisiblePosts).First(&attachment, attachmentID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Attachment not found")
+		return
+	}
+
+	key, contentType, size := attachment.StorageKey, attachment.ContentType, attachment.Size
+	if thumbnail {
+		if attachment.ThumbnailKey == "" {
+			utils.Error(c, http.StatusNotFound, "Thumbnail not found")
+			return
+		}
+		key, contentType, size = attachment.ThumbnailKey, thumbnailType(attachment.ThumbnailKey), -1
+	}
+
+	reader, err := h.Storage.Get(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("Reading attachment %d failed: %v", attachment.ID, err)
+		utils.Error(c, http.StatusNotFound, "Attachment not found")
+		return
+	}
+	defer reader.Close()
+
+	disposition := "attachment"
+	if attachment.Kind == models.AttachmentImage {
+		disposition = "inline"
+	}
+
+	c.DataFromReader(http.StatusOK, size, contentType, reader, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "public, max-age=31536000, immutable",
+	})
+}
+
+// DeleteAttachment handler for removing an attachment from a post
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	attachmentID, err := strconv.ParseUint(c.Param("attachment_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid attachment ID")
+		return
+	}
+
+	var attachment models.Attachment
+	if err := h.DB.Where("post_id = ?", postID).First(&attachment, attachmentID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Attachment not found")
+		return
+	}
+
+	if attachment.UserID != userID.(uint) {
+		utils.Error(c, http.StatusForbidden, "Only author can delete this attachment")
+		return
+	}
+
+	// the stored objects are kept until the row is purged
+	if err := h.DB.Delete(&attachment).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to delete attachment")
+		return
+	}
+
+	utils.Success(c, 200, "Attachment deleted successfully", nil)
+}
+
+func isAllowedType(detected *mimetype.MIME, allowed []string) bool {
+	for _, t := range allowed {
+		if detected.Is(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func thumbnailExtension(contentType string) string {
+	if contentType == "image/jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+func thumbnailType(key string) string {
+	if filepath.Ext(key) == ".jpg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// truncateName keeps the end of a long file name, which has its extension.
+func truncateName(name string) string {
+	return utils.TruncateStart(name, 255)
+}
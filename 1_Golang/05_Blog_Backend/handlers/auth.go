@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"blog-backend/models"
+	"blog-backend/services"
 	"blog-backend/utils"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -24,9 +27,25 @@ type LoginRequest struct {
 	Password string
 }
 
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
 type AuthResponse struct {
 	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	User  AccountUser `json:"user"`
+}
+
+// AccountUser is the signed-in user's own account, with the email that others
+// are not shown.
+type AccountUser struct {
+	models.User
+	Email string `json:"email"`
+}
+
+func newAccountUser(user models.User) AccountUser {
+	return AccountUser{User: user, Email: user.Email}
 }
 
 // Register handler for user registration
@@ -37,6 +56,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	if models.ReservedForErasure(req.Username, req.Email) {
+		utils.Error(c, http.StatusBadRequest, "Username or email is reserved")
+		return
+	}
+
 	var existingUser models.User
 	if err := h.DB.Where("Username = ?", req.Username).First(&existingUser).Error; err == nil {
 		utils.Error(c, http.StatusConflict, "Username already exists")
@@ -60,6 +84,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	services.RecordAuditAs(h.DB, c, user.ID, models.AuditUserRegister, "user", user.ID, nil)
+
 	token, err := utils.GenerateToken(user.ID, user.Username)
 
 	if err != nil {
@@ -69,7 +95,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	utils.Success(c, 200, "Registration successful", AuthResponse{
 		Token: token,
-		User:  user,
+		User:  newAccountUser(user),
 	})
 }
 
@@ -82,6 +108,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	var existingUser models.User
 	if err := h.DB.Where("Username = ?", req.Username).First(&existingUser).Error; err != nil {
+		services.RecordAudit(h.DB, c, models.AuditUserLoginFailed, "user", 0, gin.H{"username": req.Username})
 		utils.Error(c, http.StatusUnauthorized, "Invalid username or password")
 		return
 	}
@@ -89,10 +116,23 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	passed := utils.CheckPassword(existingUser.Password, req.Password)
 
 	if !passed {
+		services.RecordAudit(h.DB, c, models.AuditUserLoginFailed, "user", existingUser.ID, gin.H{"username": req.Username})
 		utils.Error(c, http.StatusUnauthorized, "Invalid username or password")
 		return
 	}
 
+	if existingUser.Blocked(time.Now()) {
+		utils.Error(c, http.StatusForbidden, existingUser.BlockedMessage())
+		return
+	}
+
+	if existingUser.PasswordResetRequired {
+		utils.Error(c, http.StatusForbidden, "Password reset required")
+		return
+	}
+
+	services.RecordAuditAs(h.DB, c, existingUser.ID, models.AuditUserLogin, "user", existingUser.ID, nil)
+
 	token, err := utils.GenerateToken(existingUser.ID, existingUser.Username)
 
 	if err != nil {
@@ -102,6 +142,55 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	utils.Success(c, 200, "Login successful", AuthResponse{
 		Token: token,
-		User:  existingUser,
+		User:  newAccountUser(existingUser),
 	})
 }
+
+// ResetPassword handler for choosing a new password with a reset token issued by an admin
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	var reset models.PasswordReset
+	if err := h.DB.Where("token_hash = ?", utils.HashToken(req.Token)).First(&reset).Error; err != nil || time.Now().After(reset.ExpiresAt) {
+		utils.Error(c, http.StatusBadRequest, "Invalid or expired reset token")
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// the token is single use, losing the race to a concurrent request fails the reset
+		result := tx.Delete(&reset)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&models.User{}).Where("id = ?", reset.UserID).Updates(map[string]any{
+			"password":                hashedPassword,
+			"password_reset_required": false,
+			"sessions_valid_after":    time.Now(),
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.Error(c, http.StatusBadRequest, "Invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	services.RecordAuditAs(h.DB, c, reset.UserID, models.AuditUserPasswordChange, "user", reset.UserID, nil)
+
+	utils.Success(c, 200, "Password reset successfully", nil)
+}
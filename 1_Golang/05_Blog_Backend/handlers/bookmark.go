@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/utils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type BookmarkHandler struct {
+	DB *gorm.DB
+}
+
+// AddBookmark handler for bookmarking a post
+func (h *BookmarkHandler) AddBookmark(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var post models.Post
+	if err := h.DB.Select("id").Scopes(models.VisiblePosts).First(&post, postID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	bookmark := models.Bookmark{UserID: userID.(uint), PostID: post.ID}
+	err = h.DB.Where(&bookmark).First(&bookmark).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = h.DB.Create(&bookmark).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			err = nil
+		}
+	}
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to add bookmark")
+		return
+	}
+
+	utils.Success(c, 200, "Bookmark added successfully", nil)
+}
+
+// RemoveBookmark handler for removing a bookmark
+func (h *BookmarkHandler) RemoveBookmark(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	result := h.DB.Where("user_id = ? AND post_id = ?", userID, postID).Delete(&models.Bookmark{})
+	if result.Error != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to remove bookmark")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.Error(c, http.StatusNotFound, "Bookmark not found")
+		return
+	}
+
+	utils.Success(c, 200, "Bookmark removed successfully", nil)
+}
+
+// ListBookmarks handler for listing the current user's bookmarked posts
+func (h *BookmarkHandler) ListBookmarks(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	page, pageSize := parsePagination(c)
+
+	// bookmarks of deleted posts and posts hidden by moderation are left out
+	query := h.DB.Model(&models.Bookmark{}).
+		Joins("JOIN posts ON posts.id = bookmarks.post_id AND posts.deleted_at IS NULL").
+		Scopes(models.VisiblePosts).
+		Where("bookmarks.user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch bookmarks")
+		return
+	}
+
+	var bookmarks []models.Bookmark
+	if err := query.Preload("Post.User").Order("bookmarks.id desc").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&bookmarks).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch bookmarks")
+		return
+	}
+
+	posts := make([]models.Post, len(bookmarks))
+	for i := range bookmarks {
+		posts[i] = bookmarks[i].Post
+	}
+	if err := withPostReactions(h.DB, posts); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch bookmarks")
+		return
+	}
+	for i := range bookmarks {
+		bookmarks[i].Post = posts[i]
+	}
+
+	utils.Success(c, 200, "Bookmarks fetched successfully", PagedResponse{
+		Items:      bookmarks,
+		Pagination: Pagination{Page: page, PageSize: pageSize, Total: total},
+	})
+}
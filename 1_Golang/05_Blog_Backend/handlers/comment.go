@@ -2,19 +2,30 @@ package handlers
 
 import (
 	"blog-backend/models"
+	"blog-backend/services"
 	"blog-backend/utils"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type CommentHandler struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Notifier *services.NotificationService
+	Hub      *services.Hub
+	Webhooks *services.WebhookService
+	Filter   *services.ContentPipeline
 }
 
 type CreateCommentRequest struct {
+	Content  string `json:"content" binding:"required,min=1,max=1000"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+type UpdateCommentRequest struct {
 	Content string `json:"content" binding:"required,min=1,max=1000"`
 }
 
@@ -32,7 +43,7 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 	}
 
 	var post models.Post
-	if err := h.DB.First(&post, postID).Error; err != nil {
+	if err := h.DB.Scopes(models.VisiblePosts).First(&post, postID).Error; err != nil {
 		utils.Error(c, http.StatusNotFound, "Post not found")
 		return
 	}
@@ -43,17 +54,55 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		return
 	}
 
+	// replies must answer a comment of the same post
+	if req.ParentID != nil {
+		var parent models.Comment
+		if err := h.DB.Scopes(models.VisibleComments).Where("post_id = ?", post.ID).First(&parent, *req.ParentID).Error; err != nil {
+			utils.Error(c, http.StatusBadRequest, "Parent comment not found")
+			return
+		}
+	}
+
 	comment := models.Comment{
 		Content:     req.Content,
 		CommenterID: userID.(uint),
 		PostId:      post.ID,
+		ParentID:    req.ParentID,
 	}
 
-	if err := h.DB.Create(&comment).Error; err != nil {
+	decision, ok := screenContent(c, h.DB, h.Filter, &services.Submission{
+		TargetType: models.TargetComment,
+		Content:    comment.Content,
+	})
+	if !ok {
+		return
+	}
+	comment.Hidden = decision.Verdict == services.VerdictHold
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		if comment.Hidden {
+			return services.HoldContent(tx, models.TargetComment, comment.ID, decision)
+		}
+		return h.Webhooks.Enqueue(tx, models.WebhookCommentCreated, post.UserID, comment)
+	})
+	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, "Failed to create comment")
 		return
 	}
 
+	services.RecordAudit(h.DB, c, models.AuditCommentCreate, "comment", comment.ID, nil)
+
+	// held comments stay quiet until a moderator approves them
+	if comment.Hidden {
+		utils.Success(c, http.StatusAccepted, "Comment is held for moderation", comment)
+		return
+	}
+	h.Notifier.NotifyComment(&post, &comment)
+	h.Hub.Publish(services.PostTopic(post.ID), services.Event{Type: services.EventCommentCreated, Data: comment})
+
 	utils.Success(c, 200, "Comment created successfully", comment)
 }
 
@@ -66,16 +115,146 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 
 	var post models.Post
 
-	if err := h.DB.First(&post, postID).Error; err != nil {
+	if err := h.DB.Scopes(models.VisiblePosts).First(&post, postID).Error; err != nil {
 		utils.Error(c, http.StatusNotFound, "Post not found")
 		return
 	}
 
 	var comments []models.Comment
-	if err := h.DB.Where("post_id = ?", postID).Preload("Commenter").Find(&comments).Error; err != nil {
+	if err := h.DB.Scopes(models.VisibleComments).Where("post_id = ?", postID).Preload("Commenter").Find(&comments).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch comments")
+		return
+	}
+
+	if err := withCommentReactions(h.DB, comments); err != nil {
 		utils.Error(c, http.StatusInternalServerError, "Failed to fetch comments")
 		return
 	}
 
-	utils.Success(c, 200, "Comments fetched successfully", comments)
+	var updated time.Time
+	for _, comment := range comments {
+		updated = latestUpdate(updated, comment.UpdatedAt)
+	}
+	respondCached(c, "Comments fetched successfully", comments, "", updated)
+}
+
+// UpdateComment handler for updating a comment
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	comment, ok := h.findComment(c)
+	if !ok {
+		return
+	}
+
+	if comment.CommenterID != userID.(uint) {
+		utils.Error(c, http.StatusForbidden, "Only commenter can update this comment")
+		return
+	}
+
+	var req UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	decision, ok := screenContent(c, h.DB, h.Filter, &services.Submission{
+		TargetType: models.TargetComment,
+		TargetID:   comment.ID,
+		Content:    req.Content,
+	})
+	if !ok {
+		return
+	}
+	held := decision.Verdict == services.VerdictHold
+
+	changes := services.Changes{}.Add("content", comment.Content, req.Content)
+	comment.Content = req.Content
+	if held {
+		comment.Hidden = true
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&comment).Error; err != nil {
+			return err
+		}
+		if held {
+			return services.HoldContent(tx, models.TargetComment, comment.ID, decision)
+		}
+		return nil
+	})
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to update comment")
+		return
+	}
+
+	services.RecordAudit(h.DB, c, models.AuditCommentUpdate, "comment", comment.ID, changes)
+
+	if held {
+		utils.Success(c, http.StatusAccepted, "Comment is held for moderation", comment)
+		return
+	}
+	if !comment.Hidden {
+		h.Hub.Publish(services.PostTopic(comment.PostId), services.Event{Type: services.EventCommentUpdated, Data: comment})
+	}
+
+	utils.Success(c, 200, "Comment updated successfully", comment)
+}
+
+// DeleteComment handler for deleting a comment
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	comment, ok := h.findComment(c)
+	if !ok {
+		return
+	}
+
+	if comment.CommenterID != userID.(uint) {
+		utils.Error(c, http.StatusForbidden, "Only commenter can delete this comment")
+		return
+	}
+
+	if err := h.DB.Delete(&comment).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to delete comment")
+		return
+	}
+
+	services.RecordAudit(h.DB, c, models.AuditCommentDelete, "comment", comment.ID, gin.H{"content": comment.Content})
+	h.Hub.Publish(services.PostTopic(comment.PostId), services.Event{Type: services.EventCommentDeleted, Data: gin.H{"id": comment.ID}})
+
+	utils.Success(c, 200, "Comment deleted successfully", nil)
+}
+
+// findComment loads the comment from the post_id and comment_id URL parameters,
+// writing the error response when it cannot be found.
+func (h *CommentHandler) findComment(c *gin.Context) (models.Comment, bool) {
+	var comment models.Comment
+
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return comment, false
+	}
+
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid comment ID")
+		return comment, false
+	}
+
+	if err := h.DB.Where("post_id = ?", postID).First(&comment, commentID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Comment not found")
+		return comment, false
+	}
+
+	return comment, true
 }
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"blog-backend/utils"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondCached responds like utils.Success with validators, or with 304 when
+// the client's copy is current. The ETag is a digest of the data, prefixed by
+// the version when the resource has one (see postVersion), so it changes with
+// everything shown, reaction counts included. Last-Modified follows UpdatedAt
+// only, so clients sending If-Modified-Since without If-None-Match may not see
+// new reactions or moderation changes until the content itself changes.
+func respondCached(c *gin.Context, message string, data any, version string, lastModified time.Time) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	tag := hex.EncodeToString(sum[:12])
+	if version != "" {
+		tag = version + "." + tag
+	}
+	if notModified(c, `"`+tag+`"`, lastModified) {
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.Response{Code: http.StatusOK, Message: message, Data: json.RawMessage(body)})
+}
+
+// postVersion identifies the state of the post itself, as opposed to its
+// comments and reactions. It leads the post's ETag and is what If-Match is
+// compared with.
+func postVersion(updatedAt time.Time) string {
+	return strconv.FormatInt(updatedAt.UnixNano(), 36)
+}
+
+// ifMatch reports whether the request may modify a post at version: when it
+// has no If-Match header, or one naming the version, e.g. the ETag of GetPost.
+// Only the version part is compared, so an edit does not conflict with new
+// comments or reactions. Weak tags never match (RFC 9110 13.1.1).
+func ifMatch(c *gin.Context, version string) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if v, _, _ := strings.Cut(strings.Trim(candidate, `"`), "."); v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// latestUpdate returns the newest of the times, for Last-Modified.
+func latestUpdate(times ...time.Time) time.Time {
+	var latest time.Time
+	for _, t := range times {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// notModified sets the ETag and Last-Modified validators and reports whether
+// the client's cached copy is still current, in which case 304 has been sent.
+// If-None-Match takes precedence over If-Modified-Since (RFC 9110 13.2.2).
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err != nil ||
+		lastModified.IsZero() || lastModified.Truncate(time.Second).After(since) {
+		return false
+	}
+
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatches does the weak comparison of If-None-Match against the current ETag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
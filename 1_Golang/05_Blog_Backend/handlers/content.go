@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"blog-backend/services"
+	"blog-backend/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// screenContent runs the content filters on a submission of the current user.
+// It writes the error response and returns false when the content is rejected.
+func screenContent(c *gin.Context, db *gorm.DB, filter *services.ContentPipeline, submission *services.Submission) (services.Decision, bool) {
+	if err := db.First(&submission.Author, c.GetUint("userID")).Error; err != nil {
+		utils.Error(c, http.StatusUnauthorized, "User not found")
+		return services.Decision{}, false
+	}
+
+	decision, err := filter.Evaluate(c.Request.Context(), submission)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to check content")
+		return decision, false
+	}
+
+	if decision.Verdict == services.VerdictReject {
+		utils.Error(c, http.StatusUnprocessableEntity, "Content rejected: "+strings.Join(decision.Reasons, "; "))
+		return decision, false
+	}
+	return decision, true
+}
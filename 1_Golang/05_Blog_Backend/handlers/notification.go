@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationHandler struct {
+	DB       *gorm.DB
+	Notifier *services.NotificationService
+}
+
+type NotificationListResponse struct {
+	PagedResponse
+	UnreadCount int64 `json:"unread_count"`
+}
+
+type UpdatePreferenceRequest struct {
+	Comments  *bool `json:"comments"`
+	Replies   *bool `json:"replies"`
+	Reactions *bool `json:"reactions"`
+	Follows   *bool `json:"follows"`
+}
+
+// ListNotifications handler for the current user's notifications, newest first
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	query := h.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	page, pageSize := parsePagination(c)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
+	}
+
+	var unread int64
+	if err := h.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&unread).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
+	}
+
+	var notifications []models.Notification
+	if err := query.Preload("Actor").Order("id desc").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&notifications).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
+	}
+
+	utils.Success(c, 200, "Notifications fetched successfully", NotificationListResponse{
+		PagedResponse: PagedResponse{
+			Items:      notifications,
+			Pagination: Pagination{Page: page, PageSize: pageSize, Total: total},
+		},
+		UnreadCount: unread,
+	})
+}
+
+// MarkNotificationRead handler for marking a single notification as read
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	notificationID, err := strconv.ParseUint(c.Param("notification_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	var notification models.Notification
+	if err := h.DB.Where("user_id = ?", userID).First(&notification, notificationID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Notification not found")
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := h.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			utils.Error(c, http.StatusInternalServerError, "Failed to mark notification as read")
+			return
+		}
+	}
+
+	utils.Success(c, 200, "Notification marked as read", notification)
+}
+
+// MarkAllNotificationsRead handler for marking every unread notification as read
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	result := h.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to mark notifications as read")
+		return
+	}
+
+	utils.Success(c, 200, "Notifications marked as read", gin.H{"updated": result.RowsAffected})
+}
+
+// GetPreferences handler for the current user's notification preferences
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	pref, err := h.Notifier.Preference(userID.(uint))
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch preferences")
+		return
+	}
+
+	utils.Success(c, 200, "Preferences fetched successfully", pref)
+}
+
+// UpdatePreferences handler for changing the current user's notification preferences
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req UpdatePreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	pref, err := h.Notifier.Preference(userID.(uint))
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to update preferences")
+		return
+	}
+
+	// only the fields present in the request are changed
+	if req.Comments != nil {
+		pref.Comments = *req.Comments
+	}
+	if req.Replies != nil {
+		pref.Replies = *req.Replies
+	}
+	if req.Reactions != nil {
+		pref.Reactions = *req.Reactions
+	}
+	if req.Follows != nil {
+		pref.Follows = *req.Follows
+	}
+
+	// upsert every column, false values would otherwise fall back to the column default
+	if err := h.DB.Clauses(clause.OnConflict{UpdateAll: true}).Select("*").Create(&pref).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to update preferences")
+		return
+	}
+
+	utils.Success(c, 200, "Preferences updated successfully", pref)
+}
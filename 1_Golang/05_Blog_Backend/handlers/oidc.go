@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/utils"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const oauthStateTTL = 10 * time.Minute
+
+type OIDCHandler struct {
+	DB       *gorm.DB
+	Provider *utils.OIDCProvider
+}
+
+// OIDCLogin handler for starting the OIDC authorization code flow with PKCE
+func (h *OIDCHandler) OIDCLogin(c *gin.Context) {
+	if h.Provider == nil {
+		utils.Error(c, http.StatusNotFound, "OIDC login is not configured")
+		return
+	}
+
+	state, err := utils.RandomString(16)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to start OIDC login")
+		return
+	}
+	nonce, err := utils.RandomString(16)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to start OIDC login")
+		return
+	}
+	verifier, err := utils.GenerateCodeVerifier()
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to start OIDC login")
+		return
+	}
+
+	authURL, err := h.Provider.AuthCodeURL(state, nonce, utils.CodeChallengeS256(verifier))
+	if err != nil {
+		utils.Error(c, http.StatusBadGateway, "OIDC provider unavailable")
+		return
+	}
+
+	// drop states of logins that were never completed
+	h.DB.Where("expires_at < ?", time.Now()).Delete(&models.OAuthState{})
+
+	if err := h.DB.Create(&models.OAuthState{
+		State:        state,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	}).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to start OIDC login")
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback handler for completing the OIDC login and issuing a JWT
+func (h *OIDCHandler) OIDCCallback(c *gin.Context) {
+	if h.Provider == nil {
+		utils.Error(c, http.StatusNotFound, "OIDC login is not configured")
+		return
+	}
+
+	if errParam := c.Query("error"); errParam != "" {
+		log.Printf("OIDC provider returned error: %s %s", errParam, c.Query("error_description"))
+		utils.Error(c, http.StatusUnauthorized, "OIDC login was denied")
+		return
+	}
+
+	code := c.Query("code")
+	stateParam := c.Query("state")
+	if code == "" || stateParam == "" {
+		utils.Error(c, http.StatusBadRequest, "Missing code or state")
+		return
+	}
+
+	// 1. consume the pending login state, it can only be used once
+	var state models.OAuthState
+	if err := h.DB.Where("state = ?", stateParam).First(&state).Error; err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid or expired state")
+		return
+	}
+	// of concurrent callbacks with the same state only the one that deletes it goes on
+	if result := h.DB.Delete(&state); result.Error != nil || result.RowsAffected != 1 {
+		utils.Error(c, http.StatusBadRequest, "Invalid or expired state")
+		return
+	}
+
+	if time.Now().After(state.ExpiresAt) {
+		utils.Error(c, http.StatusBadRequest, "Invalid or expired state")
+		return
+	}
+
+	// 2. exchange the code and verify the ID token
+	tokens, err := h.Provider.Exchange(code, state.CodeVerifier)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, "OIDC code exchange failed")
+		return
+	}
+
+	claims, err := h.Provider.VerifyIDToken(tokens.IDToken, state.Nonce)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, "Invalid ID token")
+		return
+	}
+
+	// 3. find or create the local user linked to the external identity
+	user, err := h.findOrCreateUser(claims)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to link identity")
+		return
+	}
+
+	if user.Blocked(time.Now()) {
+		utils.Error(c, http.StatusForbidden, user.BlockedMessage())
+		return
+	}
+
+	if user.PasswordResetRequired {
+		utils.Error(c, http.StatusForbidden, "Password reset required")
+		return
+	}
+
+	services.RecordAuditAs(h.DB, c, user.ID, models.AuditUserLogin, "user", user.ID, gin.H{"issuer": claims.Issuer})
+
+	token, err := utils.GenerateToken(user.ID, user.Username)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Token generation failed")
+		return
+	}
+
+	utils.Success(c, 200, "Login successful", AuthResponse{
+		Token: token,
+		User:  newAccountUser(*user),
+	})
+}
+
+// findOrCreateUser resolves the local user for the identity, linking by verified email or registering a new user.
+func (h *OIDCHandler) findOrCreateUser(claims *utils.OIDCClaims) (*models.User, error) {
+	var identity models.Identity
+	err := h.DB.Where("issuer = ? AND subject = ?", claims.Issuer, claims.Subject).
+		Preload("User").First(&identity).Error
+	if err == nil {
+		return &identity.User, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// an email the provider did not verify is neither linked nor given to a
+	// new account, where a later verified login would link to it
+	email := claims.Email
+	if !claims.EmailVerified {
+		email = ""
+	}
+
+	var user models.User
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		linked := false
+		if email != "" {
+			if err := tx.Where("email = ?", email).First(&user).Error; err == nil {
+				linked = true
+			}
+		}
+
+		if !linked {
+			username, err := uniqueUsername(tx, claims)
+			if err != nil {
+				return err
+			}
+			// the random password only passes the hook, the account has no
+			// password and signs in through the provider
+			password, err := utils.RandomString(16)
+			if err != nil {
+				return err
+			}
+			user = models.User{
+				Username: username,
+				Email:    email,
+				Password: password,
+			}
+			if user.Email == "" || models.ReservedForErasure("", user.Email) {
+				user.Email = claims.Subject + "@" + strings.TrimPrefix(strings.TrimPrefix(claims.Issuer, "https://"), "http://")
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&user).UpdateColumn("password", "").Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.Identity{
+			UserID:  user.ID,
+			Issuer:  claims.Issuer,
+			Subject: claims.Subject,
+			Email:   claims.Email,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("Linking OIDC identity failed: %v", err)
+		return nil, err
+	}
+
+	log.Printf("OIDC identity %s linked to user ID: %d", claims.Subject, user.ID)
+	return &user, nil
+}
+
+// uniqueUsername derives a free username from the ID token claims.
+func uniqueUsername(tx *gorm.DB, claims *utils.OIDCClaims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" && claims.Email != "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	if base == "" || models.ReservedForErasure(base, "") {
+		base = "user"
+	}
+
+	username := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return username, nil
+		}
+		suffix, err := utils.RandomString(3)
+		if err != nil {
+			return "", err
+		}
+		username = base + "_" + suffix
+	}
+
+	return "", errors.New("Could not find a free username.")
+}
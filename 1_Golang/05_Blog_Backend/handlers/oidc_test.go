@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/utils"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testClientID = "blog"
+
+// mockProvider is an OpenID Connect provider serving discovery, the key set
+// and a token endpoint that checks the PKCE verifier of each code.
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+// mockGrant is what the user consented to at the authorization endpoint.
+type mockGrant struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &mockProvider{t: t, key: key, codes: map[string]mockGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// authorize stands in for the user signing in at the authorization URL the
+// login redirected to, returning the code the provider sends to the callback.
+func (p *mockProvider) authorize(authURL string, claims jwt.MapClaims) (code, state string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("client_id") != testClientID || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		p.t.Fatalf("unexpected authorization request %s", authURL)
+	}
+
+	code, _ = utils.RandomString(8)
+	p.mu.Lock()
+	p.codes[code] = mockGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), claims: claims}
+	p.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	code := r.PostFormValue("code")
+	p.mu.Lock()
+	grant, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	if !ok || r.PostFormValue("client_id") != testClientID {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	if utils.CodeChallengeS256(r.PostFormValue("code_verifier")) != grant.challenge {
+		http.Error(w, `{"error":"invalid_grant","error_description":"PKCE verification failed"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   testClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": grant.nonce,
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	json.NewEncoder(w).Encode(map[string]any{"access_token": "access", "token_type": "Bearer", "id_token": idToken, "expires_in": 3600})
+}
+
+type oidcTest struct {
+	t        *testing.T
+	db       *gorm.DB
+	provider *mockProvider
+	router   *gin.Engine
+}
+
+func newOIDCTest(t *testing.T) *oidcTest {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	// every connection would open a database of its own
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.User{}, &models.Identity{}, &models.OAuthState{}, &models.AuditEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := newMockProvider(t)
+	h := &OIDCHandler{
+		DB:       db,
+		Provider: utils.NewOIDCProvider(provider.server.URL, testClientID, "", "http://blog.test/api/auth/oidc/callback"),
+	}
+	router := gin.New()
+	router.GET("/login", h.OIDCLogin)
+	router.GET("/callback", h.OIDCCallback)
+
+	return &oidcTest{t: t, db: db, provider: provider, router: router}
+}
+
+func (ot *oidcTest) get(target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	ot.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+// login starts a login and signs in at the provider with the claims,
+// returning the callback URL.
+func (ot *oidcTest) login(claims jwt.MapClaims) string {
+	w := ot.get("/login")
+	if w.Code != http.StatusFound {
+		ot.t.Fatalf("login: status %d: %s", w.Code, w.Body)
+	}
+	code, state := ot.provider.authorize(w.Header().Get("Location"), claims)
+	return "/callback?" + url.Values{"code": {code}, "state": {state}}.Encode()
+}
+
+// callback completes the login, returning the user it signed in as.
+func (ot *oidcTest) callback(callbackURL string, wantStatus int) *AccountUser {
+	w := ot.get(callbackURL)
+	if w.Code != wantStatus {
+		ot.t.Fatalf("callback: status %d, want %d: %s", w.Code, wantStatus, w.Body)
+	}
+	if w.Code != http.StatusOK {
+		return nil
+	}
+
+	var resp struct {
+		Data AuthResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		ot.t.Fatal(err)
+	}
+	if resp.Data.Token == "" {
+		ot.t.Error("callback returned no token")
+	}
+	return &resp.Data.User
+}
+
+func TestOIDCLoginCreatesAndReusesUser(t *testing.T) {
+	ot := newOIDCTest(t)
+	claims := jwt.MapClaims{"sub": "123", "email": "new@example.com", "email_verified": true, "preferred_username": "newbie"}
+
+	user := ot.callback(ot.login(claims), http.StatusOK)
+	if user.Username != "newbie" || user.Email != "new@example.com" {
+		t.Errorf("created user %q <%s>", user.Username, user.Email)
+	}
+
+	var identity models.Identity
+	if err := ot.db.Where("issuer = ? AND subject = ?", ot.provider.server.URL, "123").First(&identity).Error; err != nil {
+		t.Fatalf("identity is not linked: %v", err)
+	}
+	if identity.UserID != user.ID {
+		t.Errorf("identity linked to user %d, want %d", identity.UserID, user.ID)
+	}
+
+	again := ot.callback(ot.login(claims), http.StatusOK)
+	if again.ID != user.ID {
+		t.Errorf("second login signed in as user %d, want %d", again.ID, user.ID)
+	}
+	var count int64
+	ot.db.Model(&models.User{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d users after two logins, want 1", count)
+	}
+}
+
+func TestOIDCStateIsSingleUse(t *testing.T) {
+	ot := newOIDCTest(t)
+	callbackURL := ot.login(jwt.MapClaims{"sub": "123"})
+
+	ot.callback(callbackURL, http.StatusOK)
+	ot.callback(callbackURL, http.StatusBadRequest)
+
+	ot.callback("/callback?code=x&state=unknown", http.StatusBadRequest)
+}
+
+func TestOIDCExpiredState(t *testing.T) {
+	ot := newOIDCTest(t)
+	callbackURL := ot.login(jwt.MapClaims{"sub": "123"})
+	ot.db.Model(&models.OAuthState{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Minute))
+
+	ot.callback(callbackURL, http.StatusBadRequest)
+}
+
+func TestOIDCPKCE(t *testing.T) {
+	ot := newOIDCTest(t)
+	callbackURL := ot.login(jwt.MapClaims{"sub": "123"})
+
+	// the provider refuses the code unless the verifier matches the challenge
+	// of the authorization request
+	ot.db.Model(&models.OAuthState{}).Where("1 = 1").Update("code_verifier", "another-verifier")
+	ot.callback(callbackURL, http.StatusUnauthorized)
+
+	var count int64
+	ot.db.Model(&models.User{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d users created by a failed exchange", count)
+	}
+}
+
+func TestOIDCRejectsForeignIDToken(t *testing.T) {
+	for name, claims := range map[string]jwt.MapClaims{
+		"other audience": {"sub": "123", "aud": "other-client"},
+		"other nonce":    {"sub": "123", "nonce": "replayed"},
+		"expired":        {"sub": "123", "exp": time.Now().Add(-time.Minute).Unix()},
+		"no subject":     {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ot := newOIDCTest(t)
+			ot.callback(ot.login(claims), http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestOIDCEmailLinking(t *testing.T) {
+	tests := []struct {
+		name     string
+		verified bool
+		link     bool
+	}{
+		{"verified email links the existing account", true, true},
+		{"unverified email gets an account of its own", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ot := newOIDCTest(t)
+			existing := models.User{Username: "alice", Email: "alice@example.com", Password: "secret123"}
+			if err := ot.db.Create(&existing).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			user := ot.callback(ot.login(jwt.MapClaims{
+				"sub":                "alice-at-idp",
+				"email":              "alice@example.com",
+				"email_verified":     tt.verified,
+				"preferred_username": "alice",
+			}), http.StatusOK)
+
+			if linked := user.ID == existing.ID; linked != tt.link {
+				t.Fatalf("signed in as user %d, existing user is %d", user.ID, existing.ID)
+			}
+			if tt.link {
+				return
+			}
+			if user.Email == "alice@example.com" {
+				t.Error("the unverified email was given to the new account")
+			}
+			if user.Username == "alice" {
+				t.Error("the new account took the username of the existing one")
+			}
+
+			// a verified login for the same identity still signs in to the
+			// new account, not to the one the email belongs to
+			again := ot.callback(ot.login(jwt.MapClaims{"sub": "alice-at-idp", "email": "alice@example.com", "email_verified": true}), http.StatusOK)
+			if again.ID != user.ID {
+				t.Errorf("second login signed in as user %d, want %d", again.ID, user.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Pagination is the page metadata returned with paginated lists.
+type Pagination struct {
+	Page     int   `json:"page"`
+	PageSize int   `json:"page_size"`
+	Total    int64 `json:"total"`
+}
+
+// PagedResponse wraps a page of items and its metadata.
+type PagedResponse struct {
+	Items      any        `json:"items"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// parsePagination reads the page and page_size query parameters.
+func parsePagination(c *gin.Context) (page, pageSize int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err = strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	return page, pageSize
+}
+
+// CursorResponse wraps a page of items and the cursor of the next page, which
+// is empty on the last page.
+type CursorResponse struct {
+	Items      any    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// parseCursor reads the cursor and limit query parameters of cursor-paginated lists.
+func parseCursor(c *gin.Context) (cursor uint, limit int, ok bool) {
+	if raw := c.Query("cursor"); raw != "" {
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		cursor = uint(n)
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if err != nil || limit < 1 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	return cursor, limit, true
+}
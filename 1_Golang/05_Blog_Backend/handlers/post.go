@@ -2,26 +2,43 @@ package handlers
 
 import (
 	"blog-backend/models"
+	"blog-backend/services"
 	"blog-backend/utils"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostHandler struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Hub      *services.Hub
+	Webhooks *services.WebhookService
+	Filter   *services.ContentPipeline
+	Posts    *services.PostReader
+	Views    *services.ViewTracker
+	// AuthorScopedSlugs makes slugs unique per author instead of site-wide.
+	AuthorScopedSlugs bool
 }
 
 type CreatePostRequest struct {
-	Title   string `json:"title" binding:"required,min=1,max=200"`
-	Content string `json:"content" bingding:"required,min=1"`
+	Title         string   `json:"title" binding:"required,min=1,max=200"`
+	Content       string   `json:"content" bingding:"required,min=1"`
+	ContentFormat string   `json:"content_format" binding:"omitempty,oneof=plain markdown"`
+	Tags          []string `json:"tags" binding:"omitempty,max=10,dive,max=64"`
 }
 
 type UpdatePostRequest struct {
-	Title   string `json:"title" binding:"required,min=1,max=200"`
-	Content string `json:"content" bingding:"required,min=1"`
+	Title         string   `json:"title" binding:"required,min=1,max=200"`
+	Content       string   `json:"content" bingding:"required,min=1"`
+	ContentFormat string   `json:"content_format" binding:"omitempty,oneof=plain markdown"`
+	Tags          []string `json:"tags" binding:"omitempty,max=10,dive,max=64"`
 }
 
 // CreatePost handler for creating a new post
@@ -39,29 +56,76 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 	}
 
 	post := models.Post{
-		UserID:  userID.(uint),
-		Title:   req.Title,
-		Content: req.Content,
+		UserID:        userID.(uint),
+		Title:         req.Title,
+		Content:       req.Content,
+		ContentFormat: req.ContentFormat,
 	}
 
-	if err := h.DB.Create(&post).Error; err != nil {
+	decision, ok := screenContent(c, h.DB, h.Filter, &services.Submission{
+		TargetType: models.TargetPost,
+		Title:      post.Title,
+		Content:    post.Content,
+	})
+	if !ok {
+		return
+	}
+	post.Hidden = decision.Verdict == services.VerdictHold
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&post).Error; err != nil {
+			return err
+		}
+		if _, err := models.CreateRevision(tx, &post, post.UserID); err != nil {
+			return err
+		}
+		if err := models.AssignSlug(tx, &post, h.AuthorScopedSlugs); err != nil {
+			return err
+		}
+		if err := models.SetPostTags(tx, &post, req.Tags); err != nil {
+			return err
+		}
+		if err := tx.Joins("User").Preload("Tags").First(&post, post.ID).Error; err != nil {
+			return err
+		}
+		if post.Hidden {
+			return services.HoldContent(tx, models.TargetPost, post.ID, decision)
+		}
+		return h.Webhooks.Enqueue(tx, models.WebhookPostCreated, post.UserID, post)
+	})
+	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, "Failed to create post")
 		return
 	}
 
-	h.DB.Joins("User").First(&post, post.ID)
+	services.RecordAudit(h.DB, c, models.AuditPostCreate, "post", post.ID, nil)
+	c.Header("ETag", `"`+postVersion(post.UpdatedAt)+`"`)
+
+	if post.Hidden {
+		utils.Success(c, http.StatusAccepted, "Post is held for moderation", post)
+		return
+	}
 	utils.Success(c, 200, "Post created successfully", post)
 }
 
 // GetAllPosts handler for fetching all posts
 func (h *PostHandler) GetAllPosts(c *gin.Context) {
-	var posts []models.Post
-	if err := h.DB.Joins("User").Find(&posts).Error; err != nil {
+	posts, err := h.Posts.CachedList(c.Request.Context(), "all")
+	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, "Failed to fetch posts")
 		return
 	}
 
-	utils.Success(c, 200, "Posts fetched successfully", posts)
+	if err := withPostReactions(h.DB, posts); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch posts")
+		return
+	}
+
+	var updated time.Time
+	for _, post := range posts {
+		updated = latestUpdate(updated, post.UpdatedAt)
+	}
+	respondCached(c, "Posts fetched successfully", posts, "", updated)
 }
 
 // GetPost handler for fetching a single post
@@ -72,15 +136,100 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 		return
 	}
 
+	h.respondPost(c, uint(postID))
+}
+
+// GetPostBySlug handler for fetching a post by its slug, old slugs redirect to the current one
+func (h *PostHandler) GetPostBySlug(c *gin.Context) {
+	var slugs []models.PostSlug
+	if err := h.DB.Where("slug = ?", c.Param("slug")).Find(&slugs).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch post")
+		return
+	}
+
+	switch len(slugs) {
+	case 0:
+		utils.Error(c, http.StatusNotFound, "Post not found")
+	case 1:
+		h.respondSlug(c, slugs[0], "/api/posts/by-slug/")
+	default:
+		// only possible with author-scoped slugs
+		utils.Error(c, http.StatusConflict, "Slug is used by several authors, use /api/users/{user_id}/posts/by-slug/{slug}")
+	}
+}
+
+// GetUserPostBySlug handler for fetching a post by its author and slug
+func (h *PostHandler) GetUserPostBySlug(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var slug models.PostSlug
+	if err := h.DB.Joins("JOIN posts ON posts.id = post_slugs.post_id").
+		Where("post_slugs.slug = ? AND posts.user_id = ?", c.Param("slug"), userID).
+		First(&slug).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	h.respondSlug(c, slug, fmt.Sprintf("/api/users/%d/posts/by-slug/", userID))
+}
+
+// respondSlug responds with the post of the slug, or redirects to prefix plus
+// the post's current slug when an old one was used.
+func (h *PostHandler) respondSlug(c *gin.Context, slug models.PostSlug, prefix string) {
 	var post models.Post
-	if err := h.DB.Joins("User").Preload("Comments").First(&post, postID).Error; err != nil {
+	if err := h.DB.Scopes(models.VisiblePosts).Select("id", "slug").First(&post, slug.PostID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	if post.Slug != slug.Slug {
+		c.Redirect(http.StatusMovedPermanently, prefix+url.PathEscape(post.Slug))
+		return
+	}
+
+	h.respondPost(c, post.ID)
+}
+
+// respondPost responds with the post, its comments and reactions.
+func (h *PostHandler) respondPost(c *gin.Context, postID uint) {
+	post, err := h.Posts.CachedGet(c.Request.Context(), postID)
+	if err != nil {
 		utils.Error(c, http.StatusNotFound, "Post not found")
 		return
 	}
 
-	utils.Success(c, 200, "Post fetched successfully", post)
+	posts := []models.Post{*post}
+	if err := withPostReactions(h.DB, posts); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch post")
+		return
+	}
+	post = &posts[0]
+	if err := withCommentReactions(h.DB, post.Comments); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch post")
+		return
+	}
+
+	// authors reading their own posts are not counted
+	if userID, exists := c.Get("userID"); !exists {
+		h.Views.Record(c.Request.Context(), post.ID, "ip:"+c.ClientIP())
+	} else if userID.(uint) != post.UserID {
+		h.Views.Record(c.Request.Context(), post.ID, fmt.Sprintf("user:%d", userID))
+	}
+
+	updated := post.UpdatedAt
+	for _, comment := range post.Comments {
+		updated = latestUpdate(updated, comment.UpdatedAt)
+	}
+	respondCached(c, "Post fetched successfully", post, postVersion(post.UpdatedAt), updated)
 }
 
+// errPostModified aborts an update whose If-Match names an older version of the post.
+var errPostModified = errors.New("post modified since it was fetched")
+
 // UpdatePost handler for updating a post
 func (h *PostHandler) UpdatePost(c *gin.Context) {
 	// 1. check if user is authenticated
@@ -119,17 +268,95 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 	}
 
 	// 6. update post
+	original := post
+	changes := services.Changes{}.
+		Add("title", post.Title, req.Title).
+		Add("content", post.Content, req.Content)
 	post.Title = req.Title
 	post.Content = req.Content
+	if req.ContentFormat != "" {
+		changes.Add("content_format", post.ContentFormat, req.ContentFormat)
+		post.ContentFormat = req.ContentFormat
+	}
+
+	// 7. run the content filters, held posts are hidden until a moderator looks at them
+	decision, ok := screenContent(c, h.DB, h.Filter, &services.Submission{
+		TargetType: models.TargetPost,
+		TargetID:   post.ID,
+		Title:      post.Title,
+		Content:    post.Content,
+	})
+	if !ok {
+		return
+	}
+	held := decision.Verdict == services.VerdictHold
+	if held {
+		post.Hidden = true
+	}
 
-	// 7. save post to database
-	if err := h.DB.Save(&post).Error; err != nil {
+	// 8. save post and its new revision to database, unless it changed since
+	// the client fetched the version named in If-Match
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if c.GetHeader("If-Match") != "" {
+			var current models.Post
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "updated_at").
+				First(&current, post.ID).Error; err != nil {
+				return err
+			}
+			if !ifMatch(c, postVersion(current.UpdatedAt)) {
+				return errPostModified
+			}
+		}
+		if err := ensureBaseRevision(tx, &original); err != nil {
+			return err
+		}
+		if err := tx.Save(&post).Error; err != nil {
+			return err
+		}
+		if _, err := models.CreateRevision(tx, &post, userID.(uint)); err != nil {
+			return err
+		}
+		if post.Title != original.Title {
+			if err := models.AssignSlug(tx, &post, h.AuthorScopedSlugs); err != nil {
+				return err
+			}
+		}
+		// tags are left alone when the request does not mention them
+		if req.Tags != nil {
+			if err := models.SetPostTags(tx, &post, req.Tags); err != nil {
+				return err
+			}
+		}
+		if err := tx.Joins("User").Preload("Tags").First(&post, post.ID).Error; err != nil {
+			return err
+		}
+		if held {
+			return services.HoldContent(tx, models.TargetPost, post.ID, decision)
+		}
+		if post.Hidden {
+			return nil
+		}
+		return h.Webhooks.Enqueue(tx, models.WebhookPostUpdated, post.UserID, post)
+	})
+	if errors.Is(err, errPostModified) {
+		utils.Error(c, http.StatusPreconditionFailed, "Post has been modified since it was fetched")
+		return
+	}
+	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, "Failed to update post")
 		return
 	}
 
-	// 8. get post from database
-	h.DB.Joins("User").First(&post, post.ID)
+	services.RecordAudit(h.DB, c, models.AuditPostUpdate, "post", post.ID, changes)
+	c.Header("ETag", `"`+postVersion(post.UpdatedAt)+`"`)
+
+	if held {
+		utils.Success(c, http.StatusAccepted, "Post is held for moderation", post)
+		return
+	}
+	if !post.Hidden {
+		h.Hub.Publish(services.PostTopic(post.ID), services.Event{Type: services.EventPostUpdated, Data: post})
+	}
 	utils.Success(c, 200, "Post updated successfully", post)
 }
 
@@ -159,10 +386,41 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.Delete(&post).Error; err != nil {
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.TrashPost(tx, &post); err != nil {
+			return err
+		}
+		return enqueuePostDeleted(tx, h.Webhooks, &post)
+	})
+	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, "Failed to delete post")
 		return
 	}
 
+	services.RecordAudit(h.DB, c, models.AuditPostDelete, "post", post.ID, gin.H{"title": post.Title})
+	h.Hub.Publish(services.PostTopic(post.ID), services.Event{Type: services.EventPostDeleted, Data: gin.H{"id": post.ID}})
+
 	utils.Success(c, 200, "Post deleted successfully", nil)
 }
+
+// enqueuePostDeleted queues the post.deleted event of a post that was
+// announced. A post the content filter held when it was created never was,
+// and its deletion is not announced either, so its title is not sent out.
+func enqueuePostDeleted(tx *gorm.DB, webhooks *services.WebhookService, post *models.Post) error {
+	if post.Hidden {
+		var held int64
+		if err := tx.Model(&models.Report{}).Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status = ?",
+			0, models.TargetPost, post.ID, models.ReportOpen).Count(&held).Error; err != nil {
+			return err
+		}
+		var revisions int64
+		if err := tx.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Count(&revisions).Error; err != nil {
+			return err
+		}
+		// a post held on an update was announced when it was created
+		if held > 0 && revisions <= 1 {
+			return nil
+		}
+	}
+	return webhooks.Enqueue(tx, models.WebhookPostDeleted, post.UserID, gin.H{"id": post.ID, "title": post.Title})
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/utils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ReactionHandler struct {
+	DB       *gorm.DB
+	Notifier *services.NotificationService
+}
+
+// ReactToPost handler for adding a reaction to a post
+func (h *ReactionHandler) ReactToPost(c *gin.Context) {
+	postID, ok := h.postTarget(c)
+	if !ok {
+		return
+	}
+	h.react(c, models.TargetPost, postID)
+}
+
+// UnreactToPost handler for removing a reaction from a post
+func (h *ReactionHandler) UnreactToPost(c *gin.Context) {
+	postID, ok := h.postTarget(c)
+	if !ok {
+		return
+	}
+	h.unreact(c, models.TargetPost, postID)
+}
+
+// ReactToComment handler for adding a reaction to a comment
+func (h *ReactionHandler) ReactToComment(c *gin.Context) {
+	commentID, ok := h.commentTarget(c)
+	if !ok {
+		return
+	}
+	h.react(c, models.TargetComment, commentID)
+}
+
+// UnreactToComment handler for removing a reaction from a comment
+func (h *ReactionHandler) UnreactToComment(c *gin.Context) {
+	commentID, ok := h.commentTarget(c)
+	if !ok {
+		return
+	}
+	h.unreact(c, models.TargetComment, commentID)
+}
+
+func (h *ReactionHandler) react(c *gin.Context, targetType string, targetID uint) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	kind := c.Param("kind")
+	if !models.ValidReactionKind(kind) {
+		utils.Error(c, http.StatusBadRequest, "Unknown reaction")
+		return
+	}
+
+	reaction := models.Reaction{
+		UserID:     userID.(uint),
+		TargetType: targetType,
+		TargetID:   targetID,
+		Kind:       kind,
+	}
+
+	// reacting twice is a no-op, the unique index guards against races
+	created := false
+	err := h.DB.Where(&reaction).First(&reaction).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = h.DB.Create(&reaction).Error
+		created = err == nil
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			err = h.DB.Where(&reaction).First(&reaction).Error
+		}
+	}
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to add reaction")
+		return
+	}
+
+	if created {
+		h.Notifier.NotifyReaction(&reaction)
+	}
+
+	utils.Success(c, 200, "Reaction added successfully", reaction)
+}
+
+func (h *ReactionHandler) unreact(c *gin.Context, targetType string, targetID uint) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var reaction models.Reaction
+	if err := h.DB.Where("user_id = ? AND target_type = ? AND target_id = ? AND kind = ?",
+		userID, targetType, targetID, c.Param("kind")).First(&reaction).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Reaction not found")
+		return
+	}
+
+	if err := h.DB.Delete(&reaction).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to remove reaction")
+		return
+	}
+
+	utils.Success(c, 200, "Reaction removed successfully", nil)
+}
+
+func (h *ReactionHandler) postTarget(c *gin.Context) (uint, bool) {
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return 0, false
+	}
+
+	var post models.Post
+	if err := h.DB.Select("id").Scopes(models.VisiblePosts).First(&post, postID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found")
+		return 0, false
+	}
+
+	return post.ID, true
+}
+
+func (h *ReactionHandler) commentTarget(c *gin.Context) (uint, bool) {
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return 0, false
+	}
+
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid comment ID")
+		return 0, false
+	}
+
+	var comment models.Comment
+	if err := h.DB.Select("comments.id").Joins("JOIN posts ON posts.id = comments.post_id AND posts.deleted_at IS NULL").
+		Scopes(models.VisiblePosts, models.VisibleComments).Where("comments.post_id = ?", postID).First(&comment, commentID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Comment not found")
+		return 0, false
+	}
+
+	return comment.ID, true
+}
+
+// withPostReactions embeds the reaction counts into the posts.
+func withPostReactions(db *gorm.DB, posts []models.Post) error {
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+
+	counts, err := models.LoadReactionCounts(db, models.TargetPost, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Reactions = reactionsOrEmpty(counts[posts[i].ID])
+	}
+	return nil
+}
+
+// withCommentReactions embeds the reaction counts into the comments.
+func withCommentReactions(db *gorm.DB, comments []models.Comment) error {
+	ids := make([]uint, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+
+	counts, err := models.LoadReactionCounts(db, models.TargetComment, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range comments {
+		comments[i].Reactions = reactionsOrEmpty(counts[comments[i].ID])
+	}
+	return nil
+}
+
+func reactionsOrEmpty(counts map[string]int) map[string]int {
+	if counts == nil {
+		return map[string]int{}
+	}
+	return counts
+}
@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/utils"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ReportHandler struct {
+	DB       *gorm.DB
+	Notifier *services.NotificationService
+	Webhooks *services.WebhookService
+	Hub      *services.Hub
+	// HideThreshold is the number of open reports that hides a post or comment
+	// until a moderator looks at it, zero disables auto-hiding.
+	HideThreshold int
+}
+
+// reportActions are the moderator actions that apply to each target type.
+var reportActions = map[string][]string{
+	models.TargetPost:    {"dismiss", "hide", "delete"},
+	models.TargetComment: {"dismiss", "hide", "delete"},
+	models.TargetUser:    {"dismiss", "ban"},
+}
+
+type CreateReportRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	Detail string `json:"detail" binding:"max=1000"`
+}
+
+type ResolveReportRequest struct {
+	Action string `json:"action" binding:"required,oneof=dismiss hide delete ban"`
+	Note   string `json:"note" binding:"max=1000"`
+}
+
+// ReportPost handler for reporting a post
+func (h *ReportHandler) ReportPost(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var post models.Post
+	if err := h.DB.Select("id", "user_id").First(&post, postID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	h.createReport(c, models.TargetPost, post.ID, post.UserID)
+}
+
+// ReportComment handler for reporting a comment
+func (h *ReportHandler) ReportComment(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	var comment models.Comment
+	if err := h.DB.Select("id", "commenter_id").Where("post_id = ?", postID).First(&comment, commentID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Comment not found")
+		return
+	}
+
+	h.createReport(c, models.TargetComment, comment.ID, comment.CommenterID)
+}
+
+// ReportUser handler for reporting a user account
+func (h *ReportHandler) ReportUser(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Select("id").First(&user, targetID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	h.createReport(c, models.TargetUser, user.ID, user.ID)
+}
+
+// createReport files the report of the current user against the target owned by ownerID.
+func (h *ReportHandler) createReport(c *gin.Context, targetType string, targetID, ownerID uint) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req CreateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !models.ValidReportReason(req.Reason) {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	if ownerID == userID.(uint) {
+		utils.Error(c, http.StatusBadRequest, "You cannot report yourself")
+		return
+	}
+
+	report := models.Report{
+		ReporterID: userID.(uint),
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     req.Reason,
+		Detail:     req.Detail,
+		Status:     models.ReportOpen,
+	}
+
+	if err := h.DB.Create(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.Error(c, http.StatusConflict, "Already reported")
+			return
+		}
+		utils.Error(c, http.StatusInternalServerError, "Failed to create report")
+		return
+	}
+
+	h.autoHide(targetType, targetID)
+
+	utils.Success(c, 200, "Report created successfully", report)
+}
+
+// autoHide hides a post or comment once it collected HideThreshold open reports.
+func (h *ReportHandler) autoHide(targetType string, targetID uint) {
+	if h.HideThreshold <= 0 {
+		return
+	}
+
+	var model any
+	switch targetType {
+	case models.TargetPost:
+		model = &models.Post{}
+	case models.TargetComment:
+		model = &models.Comment{}
+	default:
+		return
+	}
+
+	var open int64
+	if err := h.DB.Model(&models.Report{}).Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, models.ReportOpen).
+		Count(&open).Error; err != nil {
+		log.Printf("Failed to count reports of %s %d: %v", targetType, targetID, err)
+		return
+	}
+	if open < int64(h.HideThreshold) {
+		return
+	}
+
+	result := h.DB.Model(model).Where("id = ? AND hidden = ?", targetID, false).UpdateColumn("hidden", true)
+	if result.Error != nil {
+		log.Printf("Failed to hide %s %d: %v", targetType, targetID, result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Hid %s %d after %d reports", targetType, targetID, open)
+	}
+}
+
+// ListReports handler for the moderation queue, oldest reports first
+func (h *ReportHandler) ListReports(c *gin.Context) {
+	query := h.DB.Model(&models.Report{}).Where("status = ?", c.DefaultQuery("status", models.ReportOpen))
+
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+
+	if targetID := c.Query("target_id"); targetID != "" {
+		id, err := strconv.ParseUint(targetID, 10, 64)
+		if err != nil {
+			utils.Error(c, http.StatusBadRequest, "Invalid target ID")
+			return
+		}
+		query = query.Where("target_id = ?", id)
+	}
+
+	page, pageSize := parsePagination(c)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch reports")
+		return
+	}
+
+	var reports []models.Report
+	if err := query.Order("id").Offset((page - 1) * pageSize).Limit(pageSize).Find(&reports).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch reports")
+		return
+	}
+
+	utils.Success(c, 200, "Reports fetched successfully", PagedResponse{
+		Items:      reports,
+		Pagination: Pagination{Page: page, PageSize: pageSize, Total: total},
+	})
+}
+
+// ResolveReport handler for a moderator decision. The decision applies to every
+// open report of the same target, and each reporter is notified.
+func (h *ReportHandler) ResolveReport(c *gin.Context) {
+	reportID, err := strconv.ParseUint(c.Param("report_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid report ID")
+		return
+	}
+
+	var req ResolveReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	var report models.Report
+	if err := h.DB.First(&report, reportID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Report not found")
+		return
+	}
+
+	if report.Status != models.ReportOpen {
+		utils.Error(c, http.StatusConflict, "Report is already resolved")
+		return
+	}
+
+	if !slices.Contains(reportActions[report.TargetType], req.Action) {
+		utils.Error(c, http.StatusBadRequest, "Action does not apply to this report")
+		return
+	}
+
+	status := models.ReportActioned
+	if req.Action == "dismiss" {
+		status = models.ReportDismissed
+	}
+	moderatorID := c.GetUint("userID")
+	now := time.Now()
+
+	var resolved []models.Report
+	var announce func()
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := h.applyAction(tx, &report, req, moderatorID); err != nil {
+			return err
+		}
+
+		if err := tx.Where("target_type = ? AND target_id = ? AND status = ?", report.TargetType, report.TargetID, models.ReportOpen).
+			Find(&resolved).Error; err != nil {
+			return err
+		}
+		// dismissing the hold of the content filter approves the content
+		if req.Action == "dismiss" && slices.ContainsFunc(resolved, func(r models.Report) bool { return r.ReporterID == 0 }) {
+			var err error
+			if announce, err = h.release(tx, &report); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Report{}).
+			Where("target_type = ? AND target_id = ? AND status = ?", report.TargetType, report.TargetID, models.ReportOpen).
+			Updates(map[string]any{
+				"status":       status,
+				"moderator_id": moderatorID,
+				"resolution":   req.Action,
+				"note":         req.Note,
+				"resolved_at":  now,
+			}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.Error(c, http.StatusNotFound, "Reported content no longer exists")
+		return
+	}
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to resolve report")
+		return
+	}
+
+	services.RecordAudit(h.DB, c, models.AuditReportResolve, report.TargetType, report.TargetID, gin.H{
+		"action":  req.Action,
+		"note":    req.Note,
+		"reports": len(resolved),
+	})
+
+	for i := range resolved {
+		resolved[i].Status = status
+		resolved[i].ModeratorID = &moderatorID
+		h.Notifier.NotifyReportResolved(&resolved[i])
+	}
+	if announce != nil {
+		announce()
+	}
+
+	h.DB.First(&report, report.ID)
+	utils.Success(c, 200, "Report resolved successfully", report)
+}
+
+// applyAction carries out the moderator's decision on the reported target.
+// Dismissing a report also lifts an automatic hide, and still works when the
+// target was deleted in the meantime.
+func (h *ReportHandler) applyAction(tx *gorm.DB, report *models.Report, req ResolveReportRequest, moderatorID uint) error {
+	err := h.applyActionToTarget(tx, report, req, moderatorID)
+	if errors.Is(err, gorm.ErrRecordNotFound) && req.Action == "dismiss" {
+		return nil
+	}
+	return err
+}
+
+// release announces held content a moderator approved, as creating or
+// updating it would have: the webhook is queued in the transaction, and the
+// returned function notifies and publishes once it is committed. Content is
+// held as it is created or updated, a post that has more than its first
+// revision and a comment edited since it was created were held on an update.
+func (h *ReportHandler) release(tx *gorm.DB, report *models.Report) (func(), error) {
+	switch report.TargetType {
+	case models.TargetPost:
+		var post models.Post
+		if err := tx.Joins("User").Preload("Tags").First(&post, report.TargetID).Error; err != nil {
+			return nil, ignoreNotFound(err)
+		}
+		var revisions int64
+		if err := tx.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Count(&revisions).Error; err != nil {
+			return nil, err
+		}
+		if revisions <= 1 {
+			return nil, h.Webhooks.Enqueue(tx, models.WebhookPostCreated, post.UserID, post)
+		}
+		if err := h.Webhooks.Enqueue(tx, models.WebhookPostUpdated, post.UserID, post); err != nil {
+			return nil, err
+		}
+		return func() {
+			h.Hub.Publish(services.PostTopic(post.ID), services.Event{Type: services.EventPostUpdated, Data: post})
+		}, nil
+
+	case models.TargetComment:
+		var comment models.Comment
+		if err := tx.First(&comment, report.TargetID).Error; err != nil {
+			return nil, ignoreNotFound(err)
+		}
+		var post models.Post
+		if err := tx.First(&post, comment.PostId).Error; err != nil {
+			return nil, ignoreNotFound(err)
+		}
+		if comment.UpdatedAt.After(comment.CreatedAt) {
+			return func() {
+				h.Hub.Publish(services.PostTopic(post.ID), services.Event{Type: services.EventCommentUpdated, Data: comment})
+			}, nil
+		}
+		if err := h.Webhooks.Enqueue(tx, models.WebhookCommentCreated, post.UserID, comment); err != nil {
+			return nil, err
+		}
+		return func() {
+			h.Notifier.NotifyComment(&post, &comment)
+			h.Hub.Publish(services.PostTopic(post.ID), services.Event{Type: services.EventCommentCreated, Data: comment})
+		}, nil
+	}
+	return nil, nil
+}
+
+// ignoreNotFound drops the error of content deleted in the meantime, which is
+// not announced.
+func ignoreNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (h *ReportHandler) applyActionToTarget(tx *gorm.DB, report *models.Report, req ResolveReportRequest, moderatorID uint) error {
+	switch report.TargetType {
+	case models.TargetPost:
+		var post models.Post
+		if err := tx.First(&post, report.TargetID).Error; err != nil {
+			return err
+		}
+		switch req.Action {
+		case "dismiss", "hide":
+			return tx.Model(&post).UpdateColumn("hidden", req.Action == "hide").Error
+		case "delete":
+			if err := models.RemovePost(tx, &post, moderatorID); err != nil {
+				return err
+			}
+			return enqueuePostDeleted(tx, h.Webhooks, &post)
+		}
+
+	case models.TargetComment:
+		var comment models.Comment
+		if err := tx.First(&comment, report.TargetID).Error; err != nil {
+			return err
+		}
+		switch req.Action {
+		case "dismiss", "hide":
+			return tx.Model(&comment).UpdateColumn("hidden", req.Action == "hide").Error
+		case "delete":
+			return models.RemoveComment(tx, &comment, moderatorID)
+		}
+
+	case models.TargetUser:
+		if req.Action == "ban" {
+			reason := req.Note
+			if reason == "" {
+				reason = "Reported for " + report.Reason
+			}
+			return tx.Model(&models.User{}).Where("id = ?", report.TargetID).Updates(map[string]any{
+				"status":        models.UserBanned,
+				"status_reason": reason,
+				"status_until":  nil,
+			}).Error
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RevisionDiffResponse struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Diff string `json:"diff"`
+}
+
+// ListRevisions handler for listing the revision history of a post
+func (h *PostHandler) ListRevisions(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var post models.Post
+	if err := h.DB.Scopes(models.VisiblePosts).First(&post, postID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	var revisions []models.PostRevision
+	if err := h.DB.Where("post_id = ?", post.ID).Order("revision desc").Find(&revisions).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch revisions")
+		return
+	}
+
+	utils.Success(c, 200, "Revisions fetched successfully", revisions)
+}
+
+// DiffRevisions handler for a unified diff between two revisions of a post
+func (h *PostHandler) DiffRevisions(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		utils.Error(c, http.StatusBadRequest, "from and to revision numbers are required")
+		return
+	}
+
+	var post models.Post
+	if err := h.DB.Scopes(models.VisiblePosts).Select("id").First(&post, postID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	var fromRev, toRev models.PostRevision
+	if err := h.DB.Where("post_id = ? AND revision = ?", post.ID, from).First(&fromRev).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Revision not found")
+		return
+	}
+	if err := h.DB.Where("post_id = ? AND revision = ?", post.ID, to).First(&toRev).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Revision not found")
+		return
+	}
+
+	diff, err := utils.UnifiedDiff(
+		fmt.Sprintf("revision %d", from),
+		fmt.Sprintf("revision %d", to),
+		"# "+fromRev.Title+"\n\n"+fromRev.Content,
+		"# "+toRev.Title+"\n\n"+toRev.Content,
+	)
+	if err != nil {
+		utils.Error(c, http.StatusRequestEntityTooLarge, "Revisions are too large to compare")
+		return
+	}
+
+	utils.Success(c, 200, "Diff generated successfully", RevisionDiffResponse{
+		From: from,
+		To:   to,
+		Diff: diff,
+	})
+}
+
+// RestoreRevision handler for restoring a post to an older revision, recorded as a new revision
+func (h *PostHandler) RestoreRevision(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	revisionNumber, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid revision")
+		return
+	}
+
+	var post models.Post
+	if err := h.DB.First(&post, postID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	if post.UserID != userID.(uint) {
+		utils.Error(c, http.StatusForbidden, "Only author can restore this post")
+		return
+	}
+
+	var revision models.PostRevision
+	if err := h.DB.Where("post_id = ? AND revision = ?", post.ID, revisionNumber).First(&revision).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Revision not found")
+		return
+	}
+
+	changes := services.Changes{}.
+		Add("title", post.Title, revision.Title).
+		Add("content", post.Content, revision.Content)
+	post.Title = revision.Title
+	post.Content = revision.Content
+
+	var restored *models.PostRevision
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&post).Error; err != nil {
+			return err
+		}
+		if restored, err = models.CreateRevision(tx, &post, userID.(uint)); err != nil {
+			return err
+		}
+		if _, ok := changes["title"]; ok {
+			if err := models.AssignSlug(tx, &post, h.AuthorScopedSlugs); err != nil {
+				return err
+			}
+		}
+		return h.Webhooks.Enqueue(tx, models.WebhookPostUpdated, post.UserID, post)
+	})
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to restore revision")
+		return
+	}
+
+	services.RecordAudit(h.DB, c, models.AuditPostUpdate, "post", post.ID, gin.H{"restored_revision": revision.Revision, "changes": changes})
+	h.Hub.Publish(services.PostTopic(post.ID), services.Event{Type: services.EventPostUpdated, Data: post})
+
+	utils.Success(c, 200, "Revision restored successfully", restored)
+}
+
+// ensureBaseRevision snapshots posts created before revisions were tracked, so
+// that their original text is not lost on the first update.
+func ensureBaseRevision(tx *gorm.DB, post *models.Post) error {
+	var count int64
+	if err := tx.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err := models.CreateRevision(tx, post, post.UserID)
+	return err
+}
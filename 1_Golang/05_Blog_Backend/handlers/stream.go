@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/utils"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+)
+
+const (
+	streamHeartbeat    = 25 * time.Second
+	socketWriteTimeout = 10 * time.Second
+)
+
+type StreamHandler struct {
+	DB  *gorm.DB
+	Hub *services.Hub
+}
+
+// PostEvents handler for streaming post and comment events over Server-Sent Events
+func (h *StreamHandler) PostEvents(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var post models.Post
+	if err := h.DB.Scopes(models.VisiblePosts).Select("id").First(&post, postID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	sub := h.Hub.Subscribe(services.PostTopic(post.ID))
+	defer sub.Cancel()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				// dropped as a slow subscriber, the client reconnects and refetches
+				return false
+			}
+			c.SSEvent(event.Type, event.Data)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// NotificationSocket handler for pushing the current user's notifications over a WebSocket
+func (h *StreamHandler) NotificationSocket(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			h.serveNotifications(ws, userID.(uint))
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+func (h *StreamHandler) serveNotifications(ws *websocket.Conn, userID uint) {
+	defer ws.Close()
+
+	sub := h.Hub.Subscribe(services.UserTopic(userID))
+	defer sub.Cancel()
+
+	// the client only sends close frames, reading detects the disconnect
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var discard string
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var event services.Event
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				log.Printf("Closing notification socket of slow user %d", userID)
+				return
+			}
+			event = e
+		case <-heartbeat.C:
+			event = services.Event{Type: "ping", Data: time.Now().Unix()}
+		case <-closed:
+			return
+		}
+
+		// a client that cannot take a message in time is disconnected
+		ws.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+		if err := websocket.JSON.Send(ws, event); err != nil {
+			log.Printf("Notification socket of user %d closed: %v", userID, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/utils"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SyndicationHandler serves RSS, Atom and JSON feeds and the sitemap of published posts.
+type SyndicationHandler struct {
+	DB        *gorm.DB
+	Posts     *services.PostReader
+	SiteURL   string
+	SiteTitle string
+}
+
+// SiteFeed handler for the feed of all posts
+func (h *SyndicationHandler) SiteFeed(c *gin.Context) {
+	h.serveFeed(c, services.SiteFeed(h.SiteTitle, h.SiteURL), "site")
+}
+
+// AuthorFeed handler for the feed of one author's posts
+func (h *SyndicationHandler) AuthorFeed(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	h.serveFeed(c, &services.Syndication{
+		Title:       fmt.Sprintf("%s - %s", h.SiteTitle, user.Username),
+		Description: "Latest posts by " + user.Username,
+		HomeURL:     h.SiteURL + services.AuthorPath(user.ID),
+	}, fmt.Sprintf("user:%d", user.ID), services.ByAuthor(user.ID))
+}
+
+// TagFeed handler for the feed of posts with a tag
+func (h *SyndicationHandler) TagFeed(c *gin.Context) {
+	var tag models.Tag
+	if err := h.DB.Where("name = ?", strings.ToLower(c.Param("tag"))).First(&tag).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Tag not found")
+		return
+	}
+
+	h.serveFeed(c, &services.Syndication{
+		Title:       fmt.Sprintf("%s - #%s", h.SiteTitle, tag.Name),
+		Description: "Latest posts tagged " + tag.Name,
+		HomeURL:     h.SiteURL + services.TagPath(tag.Name),
+	}, fmt.Sprintf("tag:%d", tag.ID), services.ByTag(tag.ID))
+}
+
+// serveFeed loads the newest posts matching the scopes, cached by key, and renders them in the
+// format named by the request path's extension, answering 304 when the
+// client's copy is current.
+func (h *SyndicationHandler) serveFeed(c *gin.Context, feed *services.Syndication, key string, scopes ...services.PostScope) {
+	format := strings.TrimPrefix(path.Ext(c.Request.URL.Path), ".")
+	contentType, ok := services.FeedContentTypes[format]
+	if !ok {
+		utils.Error(c, http.StatusNotFound, "Feed not found")
+		return
+	}
+
+	posts, err := h.Posts.CachedList(c.Request.Context(), "feed:"+key, append(scopes, services.Newest(services.FeedSize))...)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch posts")
+		return
+	}
+
+	// the validators change whenever a post enters, leaves or is edited
+	digest := sha256.New()
+	fmt.Fprintf(digest, "%s|%s|", format, feed.Title)
+	for _, post := range posts {
+		fmt.Fprintf(digest, "%d:%d|", post.ID, post.UpdatedAt.UnixNano())
+		if post.UpdatedAt.After(feed.Updated) {
+			feed.Updated = post.UpdatedAt
+		}
+	}
+	etag := `W/"` + hex.EncodeToString(digest.Sum(nil)[:16]) + `"`
+	if notModified(c, etag, feed.Updated) {
+		return
+	}
+
+	feed.FeedURL = h.SiteURL + c.Request.URL.Path
+	feed.Posts = posts
+
+	var body bytes.Buffer
+	if err := services.RenderFeed(&body, format, feed, h.SiteURL); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to render feed")
+		return
+	}
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
+
+// Sitemap handler for sitemap.xml
+func (h *SyndicationHandler) Sitemap(c *gin.Context) {
+	sitemap, err := services.BuildSitemap(h.Posts, h.SiteURL)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to build sitemap")
+		return
+	}
+
+	var body bytes.Buffer
+	if err := sitemap.Write(&body); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to build sitemap")
+		return
+	}
+
+	sum := sha256.Sum256(body.Bytes())
+	if notModified(c, `W/"`+hex.EncodeToString(sum[:16])+`"`, sitemap.Updated) {
+		return
+	}
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", body.Bytes())
+}
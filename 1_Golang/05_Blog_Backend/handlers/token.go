@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TokenHandler struct {
+	DB *gorm.DB
+}
+
+type CreateTokenRequest struct {
+	Name          string   `json:"name" binding:"required,min=1,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0,max=365"`
+}
+
+type TokenResponse struct {
+	models.APIToken
+	Scopes []string `json:"scopes"`
+}
+
+type CreateTokenResponse struct {
+	TokenResponse
+	Token string `json:"token"`
+}
+
+// CreateToken handler for creating a personal access token
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !models.ValidScope(scope) {
+			utils.Error(c, http.StatusBadRequest, "Unknown scope: "+scope)
+			return
+		}
+	}
+
+	secret, err := utils.RandomString(32)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to create token")
+		return
+	}
+	plain := models.APITokenPrefix + secret
+
+	token := models.APIToken{
+		UserID:    userID.(uint),
+		Name:      req.Name,
+		TokenHash: utils.HashToken(plain),
+		Prefix:    plain[:len(models.APITokenPrefix)+6],
+		Scopes:    strings.Join(req.Scopes, " "),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := h.DB.Create(&token).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to create token")
+		return
+	}
+
+	utils.Success(c, 200, "Token created successfully", CreateTokenResponse{
+		TokenResponse: TokenResponse{APIToken: token, Scopes: token.ScopeList()},
+		Token:         plain,
+	})
+}
+
+// ListTokens handler for listing the current user's personal access tokens
+func (h *TokenHandler) ListTokens(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var tokens []models.APIToken
+	if err := h.DB.Where("user_id = ?", userID).Order("id desc").Find(&tokens).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch tokens")
+		return
+	}
+
+	resp := make([]TokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		resp = append(resp, TokenResponse{APIToken: token, Scopes: token.ScopeList()})
+	}
+
+	utils.Success(c, 200, "Tokens fetched successfully", resp)
+}
+
+// RevokeToken handler for revoking a personal access token
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("token_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid token ID")
+		return
+	}
+
+	var token models.APIToken
+	if err := h.DB.Where("user_id = ?", userID).First(&token, tokenID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Token not found")
+		return
+	}
+
+	if err := h.DB.Delete(&token).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to revoke token")
+		return
+	}
+
+	utils.Success(c, 200, "Token revoked successfully", nil)
+}
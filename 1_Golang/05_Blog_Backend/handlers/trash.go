@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TrashHandler struct {
+	DB        *gorm.DB
+	Retention time.Duration
+}
+
+// TrashedPost is a post in the trash with the time it will be purged, and
+// whether a moderator removed it, in which case it cannot be restored.
+type TrashedPost struct {
+	models.Post
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at"`
+	Removed   bool       `json:"removed_by_moderator"`
+}
+
+// TrashedComment is a comment in the trash with the time it will be purged,
+// and whether a moderator removed it.
+type TrashedComment struct {
+	models.Comment
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at"`
+	Removed   bool       `json:"removed_by_moderator"`
+}
+
+// ListTrash handler for listing the current user's deleted posts or comments
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	page, pageSize := parsePagination(c)
+
+	switch c.DefaultQuery("type", "posts") {
+	case "posts":
+		query := h.DB.Unscoped().Model(&models.Post{}).Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			utils.Error(c, http.StatusInternalServerError, "Failed to fetch trash")
+			return
+		}
+
+		var posts []models.Post
+		if err := query.Order("deleted_at desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&posts).Error; err != nil {
+			utils.Error(c, http.StatusInternalServerError, "Failed to fetch trash")
+			return
+		}
+
+		items := make([]TrashedPost, len(posts))
+		for i, post := range posts {
+			items[i] = TrashedPost{Post: post, DeletedAt: post.DeletedAt.Time, PurgeAt: h.purgeAt(post.DeletedAt.Time),
+				Removed: post.RemovedBy != nil}
+		}
+		utils.Success(c, 200, "Trash fetched successfully", PagedResponse{
+			Items:      items,
+			Pagination: Pagination{Page: page, PageSize: pageSize, Total: total},
+		})
+
+	case "comments":
+		// comments trashed with their post come back with the post
+		query := h.DB.Unscoped().Model(&models.Comment{}).
+			Joins("JOIN posts ON posts.id = comments.post_id AND posts.deleted_at IS NULL").
+			Where("comments.commenter_id = ? AND comments.deleted_at IS NOT NULL", userID)
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			utils.Error(c, http.StatusInternalServerError, "Failed to fetch trash")
+			return
+		}
+
+		var comments []models.Comment
+		if err := query.Order("comments.deleted_at desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&comments).Error; err != nil {
+			utils.Error(c, http.StatusInternalServerError, "Failed to fetch trash")
+			return
+		}
+
+		items := make([]TrashedComment, len(comments))
+		for i, comment := range comments {
+			items[i] = TrashedComment{Comment: comment, DeletedAt: comment.DeletedAt.Time, PurgeAt: h.purgeAt(comment.DeletedAt.Time),
+				Removed: comment.RemovedBy != nil}
+		}
+		utils.Success(c, 200, "Trash fetched successfully", PagedResponse{
+			Items:      items,
+			Pagination: Pagination{Page: page, PageSize: pageSize, Total: total},
+		})
+
+	default:
+		utils.Error(c, http.StatusBadRequest, "Invalid trash type")
+	}
+}
+
+// RestorePost handler for restoring a deleted post and the comments deleted with it
+func (h *TrashHandler) RestorePost(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var post models.Post
+	if err := h.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&post, postID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Post not found in trash")
+		return
+	}
+
+	if post.UserID != userID.(uint) {
+		utils.Error(c, http.StatusForbidden, "Only author can restore this post")
+		return
+	}
+
+	if post.RemovedBy != nil {
+		utils.Error(c, http.StatusForbidden, "Post was removed by a moderator")
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		return models.RestorePost(tx, &post)
+	}); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to restore post")
+		return
+	}
+
+	services.RecordAudit(h.DB, c, models.AuditPostRestore, "post", post.ID, nil)
+
+	h.DB.Joins("User").First(&post, post.ID)
+	utils.Success(c, 200, "Post restored successfully", post)
+}
+
+// RestoreComment handler for restoring a deleted comment
+func (h *TrashHandler) RestoreComment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	postID, err := strconv.ParseUint(c.Param("post_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	var comment models.Comment
+	if err := h.DB.Unscoped().Where("post_id = ? AND deleted_at IS NOT NULL", postID).First(&comment, commentID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Comment not found in trash")
+		return
+	}
+
+	if comment.CommenterID != userID.(uint) {
+		utils.Error(c, http.StatusForbidden, "Only commenter can restore this comment")
+		return
+	}
+
+	if comment.RemovedBy != nil {
+		utils.Error(c, http.StatusForbidden, "Comment was removed by a moderator")
+		return
+	}
+
+	var post models.Post
+	if err := h.DB.First(&post, comment.PostId).Error; err != nil {
+		utils.Error(c, http.StatusConflict, "The post of this comment is deleted")
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		return models.RestoreComment(tx, &comment)
+	}); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to restore comment")
+		return
+	}
+
+	services.RecordAudit(h.DB, c, models.AuditCommentRestore, "comment", comment.ID, nil)
+
+	utils.Success(c, 200, "Comment restored successfully", comment)
+}
+
+// purgeAt is when an item deleted at deletedAt will be purged, nil if trash is kept forever.
+func (h *TrashHandler) purgeAt(deletedAt time.Time) *time.Time {
+	if h.Retention <= 0 {
+		return nil
+	}
+	purgeAt := deletedAt.Add(h.Retention)
+	return &purgeAt
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"blog-backend/cache"
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/utils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UserHandler struct {
+	DB       *gorm.DB
+	Feed     services.FeedSource
+	Notifier *services.NotificationService
+	Cache    *cache.Store
+}
+
+// GetProfile handler for fetching a user's public profile
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := cache.Fetch(ctx, h.Cache, h.Cache.Key(ctx, services.CacheUsers, "profile", userID), func() (models.User, error) {
+		var user models.User
+		err := h.DB.First(&user, userID).Error
+		return user, err
+	})
+	if err != nil {
+		utils.Error(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	utils.Success(c, 200, "Profile fetched successfully", user)
+}
+
+// Follow handler for following a user
+func (h *UserHandler) Follow(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	target, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	if target.ID == userID.(uint) {
+		utils.Error(c, http.StatusBadRequest, "Users cannot follow themselves")
+		return
+	}
+
+	follow := models.Follow{FollowerID: userID.(uint), FolloweeID: target.ID}
+	err := h.DB.Where(&follow).First(&follow).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = h.DB.Create(&follow).Error
+		if err == nil {
+			h.Notifier.NotifyFollow(&follow)
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			err = nil
+		}
+	}
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to follow user")
+		return
+	}
+
+	utils.Success(c, 200, "User followed successfully", nil)
+}
+
+// Unfollow handler for unfollowing a user
+func (h *UserHandler) Unfollow(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	targetID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var follow models.Follow
+	if err := h.DB.Where("follower_id = ? AND followee_id = ?", userID, targetID).First(&follow).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Not following this user")
+		return
+	}
+
+	// delete the loaded row so the AfterDelete hook can update both counters
+	if err := h.DB.Delete(&follow).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to unfollow user")
+		return
+	}
+
+	utils.Success(c, 200, "User unfollowed successfully", nil)
+}
+
+// ListFollowers handler for listing the users following a user
+func (h *UserHandler) ListFollowers(c *gin.Context) {
+	h.listFollows(c, "followee_id", "follower_id")
+}
+
+// ListFollowing handler for listing the users a user follows
+func (h *UserHandler) ListFollowing(c *gin.Context) {
+	h.listFollows(c, "follower_id", "followee_id")
+}
+
+func (h *UserHandler) listFollows(c *gin.Context, matchColumn, userColumn string) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := parsePagination(c)
+	query := h.DB.Model(&models.User{}).
+		Joins("JOIN follows ON follows."+userColumn+" = users.id").
+		Where("follows."+matchColumn+" = ?", user.ID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+
+	var users []models.User
+	if err := query.Order("follows.id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&users).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+
+	utils.Success(c, 200, "Users fetched successfully", PagedResponse{
+		Items:      users,
+		Pagination: Pagination{Page: page, PageSize: pageSize, Total: total},
+	})
+}
+
+// GetFeed handler for the current user's home feed of posts from followed authors
+func (h *UserHandler) GetFeed(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	cursor, limit, ok := parseCursor(c)
+	if !ok {
+		utils.Error(c, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+
+	posts, err := h.Feed.Feed(userID.(uint), cursor, limit)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch feed")
+		return
+	}
+
+	if err := withPostReactions(h.DB, posts); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch feed")
+		return
+	}
+
+	resp := CursorResponse{Items: posts}
+	if len(posts) == limit {
+		resp.NextCursor = strconv.FormatUint(uint64(posts[len(posts)-1].ID), 10)
+	}
+
+	utils.Success(c, 200, "Feed fetched successfully", resp)
+}
+
+func (h *UserHandler) findUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return user, false
+	}
+
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "User not found")
+		return user, false
+	}
+
+	return user, true
+}
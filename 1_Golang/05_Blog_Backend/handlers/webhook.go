@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WebhookHandler struct {
+	DB       *gorm.DB
+	Webhooks *services.WebhookService
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,max=2048"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Global bool     `json:"global"`
+}
+
+type WebhookResponse struct {
+	models.Webhook
+	Events []string `json:"events"`
+}
+
+type CreateWebhookResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+// CreateWebhook handler for registering a webhook endpoint
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		utils.Error(c, http.StatusBadRequest, "url must be an absolute http or https URL")
+		return
+	}
+
+	for _, event := range req.Events {
+		if !models.ValidWebhookEvent(event) {
+			utils.Error(c, http.StatusBadRequest, "Unknown event: "+event)
+			return
+		}
+	}
+
+	if req.Global {
+		var user models.User
+		if err := h.DB.Select("id", "role").First(&user, userID).Error; err != nil || user.Role != models.RoleAdmin {
+			utils.Error(c, http.StatusForbidden, "Only admins can register global webhooks")
+			return
+		}
+	}
+
+	secret, err := utils.RandomString(32)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+
+	webhook := models.Webhook{
+		UserID: userID.(uint),
+		URL:    req.URL,
+		Secret: secret,
+		Events: strings.Join(req.Events, " "),
+		Global: req.Global,
+		Active: true,
+	}
+
+	if err := h.DB.Create(&webhook).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+
+	utils.Success(c, 200, "Webhook created successfully", CreateWebhookResponse{
+		WebhookResponse: WebhookResponse{Webhook: webhook, Events: webhook.EventList()},
+		Secret:          secret,
+	})
+}
+
+// ListWebhooks handler for listing the current user's webhooks
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var webhooks []models.Webhook
+	if err := h.DB.Where("user_id = ?", userID).Order("id desc").Find(&webhooks).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch webhooks")
+		return
+	}
+
+	resp := make([]WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		resp = append(resp, WebhookResponse{Webhook: webhook, Events: webhook.EventList()})
+	}
+
+	utils.Success(c, 200, "Webhooks fetched successfully", resp)
+}
+
+// DeleteWebhook handler for removing a webhook, pending deliveries become dead
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(&webhook).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+
+	utils.Success(c, 200, "Webhook deleted successfully", nil)
+}
+
+// ListDeliveries handler for the delivery log of a webhook
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	query := h.DB.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	page, pageSize := parsePagination(c)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch deliveries")
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to fetch deliveries")
+		return
+	}
+
+	utils.Success(c, 200, "Deliveries fetched successfully", PagedResponse{
+		Items:      deliveries,
+		Pagination: Pagination{Page: page, PageSize: pageSize, Total: total},
+	})
+}
+
+// RedeliverDelivery handler for queueing a delivery again, including dead ones
+func (h *WebhookHandler) RedeliverDelivery(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.ParseUint(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	if err := h.DB.Where("webhook_id = ?", webhook.ID).First(&delivery, deliveryID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Delivery not found")
+		return
+	}
+
+	if err := h.DB.Model(&delivery).Updates(map[string]any{
+		"status":          models.DeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	}).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "Failed to queue delivery")
+		return
+	}
+
+	h.DB.First(&delivery, delivery.ID)
+	utils.Success(c, 200, "Delivery queued successfully", delivery)
+}
+
+func (h *WebhookHandler) findWebhook(c *gin.Context) (models.Webhook, bool) {
+	var webhook models.Webhook
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "User not authenticated")
+		return webhook, false
+	}
+
+	webhookID, err := strconv.ParseUint(c.Param("webhook_id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid webhook ID")
+		return webhook, false
+	}
+
+	if err := h.DB.Where("user_id = ?", userID).First(&webhook, webhookID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "Webhook not found")
+		return webhook, false
+	}
+
+	return webhook, true
+}
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"blog-backend/cache"
+	"blog-backend/commands"
 	"blog-backend/config"
 	"blog-backend/database"
+	"blog-backend/middleware"
 	"blog-backend/routes"
+	"blog-backend/services"
+	"blog-backend/storage"
+	"context"
+	"log"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,9 +22,71 @@ func main() {
 
 	db := database.InitDB(cfg)
 
-	router := gin.Default()
+	// registered before the commands run, so their writes invalidate a shared cache too
+	cacheBackend, err := cache.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Cache init failed: %v", err)
+	}
+	cacheStore := cache.NewStore(cacheBackend, time.Duration(cfg.CacheTTL)*time.Second)
+	if err := services.RegisterCacheInvalidation(db, cacheStore); err != nil {
+		log.Fatalf("Cache init failed: %v", err)
+	}
 
-	routes.SetupRoutes(router, db)
+	// maintenance commands, e.g. `go run main.go recount`
+	if len(os.Args) > 1 {
+		if err := commands.Run(&commands.Env{DB: db, Config: cfg}, os.Args[1:]); err != nil {
+			log.Fatalf("Command %s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	store, err := storage.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Storage init failed: %v", err)
+	}
+
+	jobs := services.NewJobQueue(db, cfg.JobWorkers, time.Duration(cfg.JobVisibilityTimeout)*time.Second)
+	webhooks := services.NewWebhookService(db, jobs, cfg.WebhookAllowPrivate)
+	accounts := services.NewAccountService(db, store, jobs)
+	jobs.Register(services.JobRecountCounters, services.RecountJob(db))
+	go jobs.Run(context.Background())
+	if cfg.RecountInterval > 0 {
+		go jobs.Every(context.Background(), services.JobRecountCounters, time.Duration(cfg.RecountInterval)*time.Second)
+	}
+	if cfg.TrashRetention() > 0 {
+		jobs.Register(services.JobPurgeTrash, services.PurgeJob(db, store, cfg.TrashRetention()))
+		go jobs.Every(context.Background(), services.JobPurgeTrash, services.TrashPurgeInterval)
+	}
+	go jobs.Every(context.Background(), services.JobPurgeDataExports, services.DataExportPurgeInterval)
+	go webhooks.Run(context.Background())
+
+	// viewers are remembered in the shared cache when there is one
+	seen := cacheBackend
+	if cfg.CacheDriver != "redis" {
+		seen = cache.NewLRU(int(cfg.CacheSize))
+	}
+	views := services.NewViewTracker(db, seen, time.Duration(cfg.ViewDedupMinutes)*time.Minute,
+		time.Duration(cfg.ViewFlushInterval)*time.Second)
+	go views.Run(context.Background())
+
+	router := gin.New()
+	router.Use(middleware.AccessLog(), gin.Recovery())
+	// client IPs count views and go into the audit log, so X-Forwarded-For is
+	// only believed from the configured proxies
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	routes.SetupRoutes(router, &routes.Dependencies{
+		DB:       db,
+		Config:   cfg,
+		Storage:  store,
+		Cache:    cacheStore,
+		Hub:      services.NewHub(),
+		Webhooks: webhooks,
+		Views:    views,
+		Accounts: accounts,
+	})
 
 	router.Run(cfg.ServerPort)
 }
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"blog-backend/models"
+	"blog-backend/utils"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AdminMiddleware only lets users with the admin role through. It must run after AuthMiddleware.
+func AdminMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			utils.Error(c, 401, "User not authenticated")
+			c.Abort()
+			return
+		}
+
+		var user models.User
+		if err := db.Select("id", "role").First(&user, userID).Error; err != nil || user.Role != models.RoleAdmin {
+			utils.Error(c, 403, "Admin permission required")
+			log.Printf("User %v denied admin access", userID)
+			c.Abort()
+			return
+		}
+
+		c.Set("role", user.Role)
+
+		c.Next()
+	}
+}
@@ -1,14 +1,24 @@
 package middleware
 
 import (
+	"blog-backend/models"
 	"blog-backend/utils"
 	"log"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// Values of the "authMethod" context key.
+const (
+	AuthMethodJWT      = "jwt"
+	AuthMethodAPIToken = "api_token"
+)
+
+func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 
@@ -28,6 +38,11 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
+		if strings.HasPrefix(tokenString, models.APITokenPrefix) {
+			authenticateAPIToken(c, db, tokenString)
+			return
+		}
+
 		claims, err := utils.ValidateToken(tokenString)
 
 		if err != nil {
@@ -37,11 +52,147 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		var user models.User
+		if err := db.Select("id", "status", "status_reason", "status_until", "sessions_valid_after").
+			First(&user, claims.UserID).Error; err != nil {
+			utils.Error(c, 401, "Invalid token")
+			log.Printf("Token of unknown user %d", claims.UserID)
+			c.Abort()
+			return
+		}
+
+		if claims.IssuedAt == nil || user.SessionRevoked(claims.IssuedAt.Time) {
+			utils.Error(c, 401, "Session expired, please log in again")
+			log.Printf("Revoked session of user %d", user.ID)
+			c.Abort()
+			return
+		}
+
+		if rejectBlocked(c, &user) {
+			return
+		}
+
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("authMethod", AuthMethodJWT)
+		c.Set("authTime", claims.IssuedAt.Time)
 
 		log.Printf("Token validated successfully")
 
 		c.Next()
 	}
 }
+
+// OptionalAuth authenticates requests that carry an Authorization header like
+// AuthMiddleware, rejecting invalid tokens, and lets anonymous requests through.
+// It is for public endpoints that tell signed-in users apart.
+func OptionalAuth(db *gorm.DB) gin.HandlerFunc {
+	auth := AuthMiddleware(db)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+
+		auth(c)
+	}
+}
+
+// authenticateAPIToken authenticates the request with a personal access token.
+func authenticateAPIToken(c *gin.Context, db *gorm.DB, tokenString string) {
+	var token models.APIToken
+	if err := db.Preload("User").Where("token_hash = ?", utils.HashToken(tokenString)).First(&token).Error; err != nil {
+		utils.Error(c, 401, "Invalid token")
+		log.Printf("Unknown or revoked API token")
+		c.Abort()
+		return
+	}
+
+	if token.Expired() {
+		utils.Error(c, 401, "Token expired")
+		log.Printf("API token %d expired", token.ID)
+		c.Abort()
+		return
+	}
+
+	if rejectBlocked(c, &token.User) {
+		return
+	}
+
+	db.Model(&token).UpdateColumn("last_used_at", time.Now())
+
+	c.Set("userID", token.UserID)
+	c.Set("username", token.User.Username)
+	c.Set("authMethod", AuthMethodAPIToken)
+	c.Set("scopes", token.ScopeList())
+
+	log.Printf("API token validated successfully")
+
+	c.Next()
+}
+
+// rejectBlocked aborts the request of a banned or suspended user.
+func rejectBlocked(c *gin.Context, user *models.User) bool {
+	if !user.Blocked(time.Now()) {
+		return false
+	}
+
+	utils.Error(c, 403, user.BlockedMessage())
+	log.Printf("Blocked user %d rejected", user.ID)
+	c.Abort()
+	return true
+}
+
+// RequireScope rejects personal access tokens that were not granted the scope.
+// JWT sessions carry every scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("authMethod") != AuthMethodAPIToken {
+			c.Next()
+			return
+		}
+
+		scopes, _ := c.Get("scopes")
+		if list, ok := scopes.([]string); ok && slices.Contains(list, scope) {
+			c.Next()
+			return
+		}
+
+		utils.Error(c, 403, "Token is missing scope "+scope)
+		log.Printf("API token is missing scope %s", scope)
+		c.Abort()
+	}
+}
+
+// RequireSession rejects personal access tokens, used for endpoints that must
+// not be reachable with a token, such as managing tokens themselves.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("authMethod") == AuthMethodAPIToken {
+			utils.Error(c, 403, "This endpoint requires a login session")
+			log.Printf("API token used on session-only endpoint")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// QueryToken lets clients that cannot set headers, such as EventSource and
+// browser WebSockets, pass the bearer token as the access_token query parameter.
+// The parameter is removed from the URL, so the access log does not record the
+// token. It must run before AuthMiddleware.
+func QueryToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		if query := c.Request.URL.Query(); query.Has("access_token") {
+			query.Del("access_token")
+			c.Request.URL.RawQuery = query.Encode()
+		}
+
+		c.Next()
+	}
+}
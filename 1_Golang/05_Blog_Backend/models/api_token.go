@@ -0,0 +1,63 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APITokenPrefix marks a bearer token as a personal access token rather than a JWT.
+const APITokenPrefix = "blog_pat_"
+
+// Scopes that can be granted to a personal access token.
+const (
+	ScopePostsWrite     = "posts:write"
+	ScopeCommentsWrite  = "comments:write"
+	ScopeReactionsWrite = "reactions:write"
+	ScopeBookmarksWrite = "bookmarks:write"
+	ScopeFollowsWrite   = "follows:write"
+	ScopeReportsWrite   = "reports:write"
+	// ScopeAccountRead reads the user's own bookmarks, feed, trash and analytics.
+	ScopeAccountRead        = "account:read"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+)
+
+var APITokenScopes = []string{ScopePostsWrite, ScopeCommentsWrite, ScopeReactionsWrite, ScopeBookmarksWrite, ScopeFollowsWrite,
+	ScopeReportsWrite, ScopeAccountRead, ScopeNotificationsRead, ScopeNotificationsWrite}
+
+// APIToken is a named, scoped personal access token. Only the SHA-256 hash of
+// the token is stored, the plain token is shown once on creation.
+type APIToken struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	UserID     uint           `gorm:"index;not null" json:"user_id"`
+	User       User           `gorm:"foreignKey:UserID" json:"-"`
+	Name       string         `gorm:"type:varchar(100);not null" json:"name"`
+	TokenHash  string         `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	Prefix     string         `gorm:"type:varchar(20);not null" json:"prefix"`
+	Scopes     string         `gorm:"type:varchar(255);not null" json:"-"`
+	ExpiresAt  *time.Time     `json:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// ScopeList returns the scopes granted to the token.
+func (t *APIToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(t.Scopes, " ")
+}
+
+// Expired reports whether the token is past its expiry.
+func (t *APIToken) Expired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
+
+// ValidScope reports whether the scope can be granted to a token.
+func ValidScope(scope string) bool {
+	return slices.Contains(APITokenScopes, scope)
+}
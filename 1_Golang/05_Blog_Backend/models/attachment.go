@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Attachment kinds.
+const (
+	AttachmentImage = "image"
+	AttachmentFile  = "file"
+)
+
+// Attachment is an uploaded image or file linked to a post. The bytes live in
+// storage under StorageKey.
+type Attachment struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	PostID       uint           `gorm:"index;not null" json:"post_id"`
+	UserID       uint           `gorm:"index;not null" json:"user_id"`
+	Kind         string         `gorm:"type:varchar(10);not null" json:"kind"`
+	FileName     string         `gorm:"type:varchar(255);not null" json:"file_name"`
+	ContentType  string         `gorm:"type:varchar(100);not null" json:"content_type"`
+	Size         int64          `json:"size"`
+	Width        int            `json:"width,omitempty"`
+	Height       int            `json:"height,omitempty"`
+	StorageKey   string         `gorm:"type:varchar(255);not null" json:"-"`
+	ThumbnailKey string         `gorm:"type:varchar(255)" json:"-"`
+	URL          string         `gorm:"-" json:"url"`
+	ThumbnailURL string         `gorm:"-" json:"thumbnail_url,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// AfterFind fills in the download URLs served by the attachment handler.
+func (a *Attachment) AfterFind(tx *gorm.DB) error {
+	a.setURLs()
+	return nil
+}
+
+func (a *Attachment) AfterCreate(tx *gorm.DB) error {
+	a.setURLs()
+	return nil
+}
+
+func (a *Attachment) setURLs() {
+	a.URL = fmt.Sprintf("/api/attachments/%d", a.ID)
+	if a.ThumbnailKey != "" {
+		a.ThumbnailURL = fmt.Sprintf("/api/attachments/%d/thumbnail", a.ID)
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit actions.
+const (
+	AuditUserRegister       = "user.register"
+	AuditUserLogin          = "user.login"
+	AuditUserLoginFailed    = "user.login_failed"
+	AuditUserRoleChange     = "user.role_change"
+	AuditUserStatusChange   = "user.status_change"
+	AuditUserPasswordReset  = "user.password_reset"
+	AuditUserPasswordChange = "user.password_change"
+	AuditUserErase          = "user.erase"
+	AuditUserDataExport     = "user.data_export"
+	AuditPostCreate         = "post.create"
+	AuditPostUpdate         = "post.update"
+	AuditPostDelete         = "post.delete"
+	AuditPostRestore        = "post.restore"
+	AuditPostImport         = "post.import"
+	AuditCommentCreate      = "comment.create"
+	AuditCommentUpdate      = "comment.update"
+	AuditCommentDelete      = "comment.delete"
+	AuditCommentRestore     = "comment.restore"
+	AuditReportResolve      = "report.resolve"
+)
+
+// AuditEvent records a security-relevant or content-changing action.
+type AuditEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ActorID    *uint     `gorm:"index" json:"actor_id"`
+	Action     string    `gorm:"type:varchar(50);index;not null" json:"action"`
+	TargetType string    `gorm:"type:varchar(50)" json:"target_type"`
+	TargetID   uint      `json:"target_id"`
+	IP         string    `gorm:"type:varchar(45)" json:"ip"`
+	UserAgent  string    `gorm:"type:varchar(255)" json:"user_agent"`
+	Diff       string    `gorm:"type:text" json:"diff,omitempty"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
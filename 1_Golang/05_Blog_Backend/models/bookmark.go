@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// Bookmark is a post saved by a user to read later.
+type Bookmark struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_bookmark_unique;not null" json:"user_id"`
+	PostID    uint      `gorm:"uniqueIndex:idx_bookmark_unique;not null" json:"post_id"`
+	Post      Post      `gorm:"foreignKey:PostID" json:"post"`
+	CreatedAt time.Time `json:"created_at"`
+}
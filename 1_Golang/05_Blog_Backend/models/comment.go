@@ -1,6 +1,7 @@
 package models
 
 import (
+	"blog-backend/utils"
 	"time"
 
 	"gorm.io/gorm"
@@ -9,11 +10,42 @@ import (
 type Comment struct {
 	ID          uint           `gorm:"primaryKey" json:"id"`
 	Content     string         `gorm:"not null" json:"content"`
+	ContentHTML string         `gorm:"type:text" json:"content_html"`
 	CommenterID uint           `json:"commenter_id"`
 	Commenter   User           `gorm:"foreignKey:CommenterID" json:"-"`
 	PostId      uint           `json:"post_id"`
 	Post        Post           `gorm:"foreignKey:PostID" json:"-"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ParentID    *uint          `gorm:"index" json:"parent_id,omitempty"`
+	Reactions   map[string]int `gorm:"-" json:"reactions"`
+	Hidden      bool           `gorm:"not null;default:false;index" json:"hidden"`
+	// RemovedBy is the moderator who deleted the comment, the commenter cannot
+	// restore such a comment from the trash.
+	RemovedBy *uint          `json:"-"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// VisibleComments is a scope that leaves out comments hidden by moderation.
+func VisibleComments(db *gorm.DB) *gorm.DB {
+	return db.Where("comments.hidden = ?", false)
+}
+
+// BeforeSave renders the comment Markdown with the restrictive comment allow-list.
+func (c *Comment) BeforeSave(tx *gorm.DB) error {
+	c.ContentHTML = utils.RenderContent(c.Content, utils.FormatMarkdown, utils.CommentPolicy)
+	return nil
+}
+
+func (c *Comment) AfterCreate(tx *gorm.DB) error {
+	return tx.Model(&Post{}).Where("id = ?", c.PostId).
+		Update("comment_count", gorm.Expr("comment_count + 1")).Error
+}
+
+func (c *Comment) AfterDelete(tx *gorm.DB) error {
+	if !deletedLiveRow(tx, c.DeletedAt) {
+		return nil
+	}
+	return tx.Model(&Post{}).Where("id = ?", c.PostId).
+		Update("comment_count", gorm.Expr("comment_count - 1")).Error
 }
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Data export states.
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+)
+
+// DataExportTTL is how long a finished export can be downloaded.
+const DataExportTTL = 24 * time.Hour
+
+// DataExportStale is how long an export may stay pending before it is given
+// up, for example because its job died, and a new one can be requested.
+const DataExportStale = time.Hour
+
+// DataExport is a ZIP archive of a user's personal data, built in the background.
+type DataExport struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"-"`
+	Status     string     `gorm:"type:varchar(20);not null" json:"status"`
+	StorageKey string     `gorm:"type:varchar(255)" json:"-"`
+	Size       int64      `json:"size,omitempty"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Downloadable reports whether the export is finished and not yet expired.
+func (e *DataExport) Downloadable(now time.Time) bool {
+	return e.Status == ExportReady && e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+}
+
+// InProgress reports whether the export is still being built.
+func (e *DataExport) InProgress(now time.Time) bool {
+	return e.Status == ExportPending && now.Sub(e.CreatedAt) < DataExportStale
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Follow means the follower receives the followee's posts in their feed.
+type Follow struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	FollowerID uint      `gorm:"uniqueIndex:idx_follow_unique;not null" json:"follower_id"`
+	FolloweeID uint      `gorm:"uniqueIndex:idx_follow_unique;index;not null" json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (f *Follow) AfterCreate(tx *gorm.DB) error {
+	if err := tx.Model(&User{}).Where("id = ?", f.FolloweeID).
+		Update("follower_count", gorm.Expr("follower_count + 1")).Error; err != nil {
+		return err
+	}
+	return tx.Model(&User{}).Where("id = ?", f.FollowerID).
+		Update("following_count", gorm.Expr("following_count + 1")).Error
+}
+
+func (f *Follow) AfterDelete(tx *gorm.DB) error {
+	if !deletedLiveRow(tx, gorm.DeletedAt{}) {
+		return nil
+	}
+	if err := tx.Model(&User{}).Where("id = ?", f.FolloweeID).
+		Update("follower_count", gorm.Expr("follower_count - 1")).Error; err != nil {
+		return err
+	}
+	return tx.Model(&User{}).Where("id = ?", f.FollowerID).
+		Update("following_count", gorm.Expr("following_count - 1")).Error
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Identity links an account at an external OIDC provider to a local user.
+type Identity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	Issuer    string    `gorm:"type:varchar(255);uniqueIndex:idx_identity_issuer_subject;not null" json:"issuer"`
+	Subject   string    `gorm:"type:varchar(255);uniqueIndex:idx_identity_issuer_subject;not null" json:"subject"`
+	Email     string    `gorm:"type:varchar(255)" json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OAuthState holds the PKCE verifier and nonce of a pending OIDC login between
+// the redirect to the provider and the callback.
+type OAuthState struct {
+	ID           uint      `gorm:"primaryKey"`
+	State        string    `gorm:"type:varchar(64);uniqueIndex;not null"`
+	CodeVerifier string    `gorm:"type:varchar(128);not null"`
+	Nonce        string    `gorm:"type:varchar(64);not null"`
+	ExpiresAt    time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
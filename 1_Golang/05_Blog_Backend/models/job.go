@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Job states.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+)
+
+// Job is a unit of background work. Jobs are inserted with the transaction of
+// the change that caused them, so the table doubles as the transactional
+// outbox: a job exists if and only if its domain change was committed.
+type Job struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	Type    string `gorm:"type:varchar(50);index;not null" json:"type"`
+	Payload string `gorm:"type:mediumtext;not null" json:"payload"`
+	// SubjectID is the user whose content the payload copies, so the job is
+	// removed when the account is erased.
+	SubjectID *uint `gorm:"index" json:"subject_id,omitempty"`
+	// DedupKey is unique among jobs, and is cleared once the job finished, so
+	// at most one job of a key is pending or running at a time.
+	DedupKey    *string    `gorm:"type:varchar(100);uniqueIndex" json:"dedup_key,omitempty"`
+	Status      string     `gorm:"type:varchar(20);index:idx_job_due;not null" json:"status"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"not null" json:"max_attempts"`
+	RunAt       time.Time  `gorm:"index:idx_job_due" json:"run_at"`
+	LockedUntil *time.Time `json:"locked_until"`
+	LastError   string     `gorm:"type:varchar(1024)" json:"last_error"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification types.
+const (
+	NotifyComment  = "comment"
+	NotifyReply    = "reply"
+	NotifyReaction = "reaction"
+	NotifyFollow   = "follow"
+	NotifyReport   = "report"
+)
+
+// Notification tells a user that someone interacted with their content or account.
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index:idx_notification_user;not null" json:"user_id"`
+	ActorID   uint       `json:"actor_id"`
+	Actor     User       `gorm:"foreignKey:ActorID" json:"actor"`
+	Type      string     `gorm:"type:varchar(20);not null" json:"type"`
+	PostID    *uint      `json:"post_id,omitempty"`
+	CommentID *uint      `json:"comment_id,omitempty"`
+	Detail    string     `gorm:"type:varchar(50)" json:"detail,omitempty"`
+	ReadAt    *time.Time `gorm:"index:idx_notification_user" json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationPreference holds which notification types a user wants to receive.
+type NotificationPreference struct {
+	UserID    uint `gorm:"primaryKey" json:"-"`
+	Comments  bool `gorm:"not null;default:true" json:"comments"`
+	Replies   bool `gorm:"not null;default:true" json:"replies"`
+	Reactions bool `gorm:"not null;default:true" json:"reactions"`
+	Follows   bool `gorm:"not null;default:true" json:"follows"`
+}
+
+// DefaultNotificationPreference enables every notification type.
+func DefaultNotificationPreference(userID uint) NotificationPreference {
+	return NotificationPreference{UserID: userID, Comments: true, Replies: true, Reactions: true, Follows: true}
+}
+
+// Wants reports whether the preference allows notifications of the type.
+func (p *NotificationPreference) Wants(notificationType string) bool {
+	switch notificationType {
+	case NotifyComment:
+		return p.Comments
+	case NotifyReply:
+		return p.Replies
+	case NotifyReaction:
+		return p.Reactions
+	case NotifyFollow:
+		return p.Follows
+	default:
+		return true
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordResetTTL is how long a reset token can be used.
+const PasswordResetTTL = 24 * time.Hour
+
+// PasswordReset is a one-time token that lets a user choose a new password.
+// Only the hash of the token is stored.
+type PasswordReset struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"uniqueIndex;not null"`
+	TokenHash string    `gorm:"type:varchar(64);uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time
+}
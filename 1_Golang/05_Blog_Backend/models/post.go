@@ -1,29 +1,70 @@
 package models
 
 import (
+	"blog-backend/utils"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type Post struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Title     string         `gorm:"not null" json:"title"`
-	Content   string         `gorm:"not null" json:"content"`
-	UserID    uint           `json:"user_id"`
-	User      User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Comments  []Comment      `gorm:"foreignKey:PostID" json:"comments,omitempty"`
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Title         string         `gorm:"not null" json:"title"`
+	Slug          string         `gorm:"type:varchar(191);index" json:"slug"`
+	Content       string         `gorm:"not null" json:"content"`
+	ContentFormat string         `gorm:"type:varchar(20);not null;default:plain" json:"content_format"`
+	ContentHTML   string         `gorm:"type:longtext" json:"content_html"`
+	UserID        uint           `json:"user_id"`
+	User          User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Comments      []Comment      `gorm:"foreignKey:PostID" json:"comments,omitempty"`
+	Tags          []Tag          `gorm:"many2many:post_tags" json:"tags,omitempty"`
+	Reactions     map[string]int `gorm:"-" json:"reactions"`
+	CommentCount  int            `gorm:"not null;default:0" json:"comment_count"`
+	ViewCount     int64          `gorm:"not null;default:0" json:"view_count"`
+	Hidden        bool           `gorm:"not null;default:false;index" json:"hidden"`
+	// RemovedBy is the moderator who deleted the post, the author cannot
+	// restore such a post from the trash.
+	RemovedBy *uint          `json:"-"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// VisiblePosts is a scope that leaves out posts hidden by moderation.
+func VisiblePosts(db *gorm.DB) *gorm.DB {
+	return db.Where("posts.hidden = ?", false)
+}
+
+// BeforeSave refreshes the cached ContentHTML, so it is rendered once per change
+// instead of on every read.
+func (p *Post) BeforeSave(tx *gorm.DB) error {
+	if p.ContentFormat == "" {
+		p.ContentFormat = utils.FormatPlain
+	}
+	p.ContentHTML = utils.RenderContent(p.Content, p.ContentFormat, utils.PostPolicy)
+	return nil
+}
+
 func (p *Post) AfterCreate(tx *gorm.DB) error {
 	return tx.Model(&User{}).Where("id = ?", p.UserID).
 		Update("post_count", gorm.Expr("post_count + 1")).Error
 }
 
 func (p *Post) AfterDelete(tx *gorm.DB) error {
+	if !deletedLiveRow(tx, p.DeletedAt) {
+		return nil
+	}
 	return tx.Model(&User{}).Where("id = ?", p.UserID).
 		Update("post_count", gorm.Expr("post_count - 1")).Error
 }
+
+// deletedLiveRow reports whether the delete that triggered an AfterDelete hook
+// removed a row that still counted, so counters are not decremented twice by
+// repeated deletes or by hard-deleting a row that was already soft-deleted.
+// Bulk deletes carry no row data and are left to the counter reconciliation.
+func deletedLiveRow(tx *gorm.DB, deletedAt gorm.DeletedAt) bool {
+	if tx.Statement.DB.RowsAffected == 0 {
+		return false
+	}
+	return !(tx.Statement.Unscoped && deletedAt.Valid)
+}
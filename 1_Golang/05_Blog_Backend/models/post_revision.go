@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostRevision is a snapshot of a post's title and content after a change.
+type PostRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PostID    uint      `gorm:"uniqueIndex:idx_post_revision;not null" json:"post_id"`
+	Revision  int       `gorm:"uniqueIndex:idx_post_revision;not null" json:"revision"`
+	EditorID  uint      `json:"editor_id"`
+	Title     string    `gorm:"not null" json:"title"`
+	Content   string    `gorm:"type:longtext;not null" json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// maxRevisionAttempts is how often a revision number taken by a concurrent
+// edit is retried.
+const maxRevisionAttempts = 3
+
+// CreateRevision stores the current state of the post as its next revision.
+func CreateRevision(tx *gorm.DB, post *Post, editorID uint) (*PostRevision, error) {
+	for attempt := 1; ; attempt++ {
+		// a locking read sees revisions committed since the transaction began
+		var last int
+		if err := tx.Model(&PostRevision{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where("post_id = ?", post.ID).
+			Select("COALESCE(MAX(revision), 0)").Scan(&last).Error; err != nil {
+			return nil, err
+		}
+
+		revision := PostRevision{
+			PostID:   post.ID,
+			Revision: last + 1,
+			EditorID: editorID,
+			Title:    post.Title,
+			Content:  post.Content,
+		}
+		err := tx.Create(&revision).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) && attempt < maxRevisionAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &revision, nil
+	}
+}
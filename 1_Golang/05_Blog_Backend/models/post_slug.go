@@ -0,0 +1,78 @@
+package models
+
+import (
+	"blog-backend/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostSlug reserves a slug for a post. A post keeps the slugs it had before a
+// title change, so old permalinks still resolve and can redirect to Post.Slug.
+// Scope is 0 when slugs are unique site-wide and the author's ID when they are
+// only unique per author.
+type PostSlug struct {
+	ID        uint   `gorm:"primaryKey"`
+	Scope     uint   `gorm:"uniqueIndex:idx_post_slug;not null"`
+	Slug      string `gorm:"type:varchar(191);uniqueIndex:idx_post_slug;not null"`
+	PostID    uint   `gorm:"index;not null"`
+	CreatedAt time.Time
+}
+
+// maxSlugAttempts is how many numbered variants are tried before a random suffix.
+const maxSlugAttempts = 20
+
+// AssignSlug gives the post a slug derived from its title, keeping the current
+// one when the title still produces it. A slug the post used before is taken
+// back; slugs of other posts get a -2, -3, ... suffix.
+func AssignSlug(tx *gorm.DB, post *Post, authorScoped bool) error {
+	base := utils.Slugify(post.Title)
+	if base == "" {
+		base = "post"
+	}
+
+	var scope uint
+	if authorScoped {
+		scope = post.UserID
+	}
+
+	for attempt := 1; ; attempt++ {
+		candidate := base
+		switch {
+		case attempt > maxSlugAttempts:
+			suffix, err := utils.RandomString(4)
+			if err != nil {
+				return err
+			}
+			candidate = base + "-" + suffix
+		case attempt > 1:
+			candidate = fmt.Sprintf("%s-%d", base, attempt)
+		}
+
+		if candidate == post.Slug {
+			return nil
+		}
+
+		var existing PostSlug
+		err := tx.Where("scope = ? AND slug = ?", scope, candidate).First(&existing).Error
+		switch {
+		case err == nil && existing.PostID != post.ID:
+			continue
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(&PostSlug{Scope: scope, Slug: candidate, PostID: post.ID}).Error; err != nil {
+				// taken by a concurrent request in the meantime
+				if errors.Is(err, gorm.ErrDuplicatedKey) {
+					continue
+				}
+				return err
+			}
+		case err != nil:
+			return err
+		}
+
+		post.Slug = candidate
+		return tx.Model(&Post{}).Where("id = ?", post.ID).UpdateColumn("slug", candidate).Error
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// PostDailyView is the number of counted views of a post on one day. The sum
+// over a post's days is its ViewCount.
+type PostDailyView struct {
+	PostID uint      `gorm:"primaryKey"`
+	Day    time.Time `gorm:"type:date;primaryKey;index"`
+	Views  int64     `gorm:"not null;default:0"`
+}
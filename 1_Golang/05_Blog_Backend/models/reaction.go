@@ -0,0 +1,77 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reaction target types.
+const (
+	TargetPost    = "post"
+	TargetComment = "comment"
+)
+
+// ReactionKinds is the set of reactions a user can leave.
+var ReactionKinds = []string{"like", "love", "laugh", "hooray", "surprised", "sad"}
+
+// Reaction is a user's reaction of one kind to a post or comment.
+type Reaction struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"uniqueIndex:idx_reaction_unique;not null" json:"user_id"`
+	TargetType string    `gorm:"type:varchar(20);uniqueIndex:idx_reaction_unique;index:idx_reaction_target;not null" json:"target_type"`
+	TargetID   uint      `gorm:"uniqueIndex:idx_reaction_unique;index:idx_reaction_target;not null" json:"target_id"`
+	Kind       string    `gorm:"type:varchar(20);uniqueIndex:idx_reaction_unique;not null" json:"kind"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ReactionCount is the denormalized number of reactions of a kind on a target.
+type ReactionCount struct {
+	TargetType string `gorm:"type:varchar(20);primaryKey"`
+	TargetID   uint   `gorm:"primaryKey"`
+	Kind       string `gorm:"type:varchar(20);primaryKey"`
+	Total      int    `gorm:"not null;default:0"`
+}
+
+// ValidReactionKind reports whether kind is one of ReactionKinds.
+func ValidReactionKind(kind string) bool {
+	return slices.Contains(ReactionKinds, kind)
+}
+
+func (r *Reaction) AfterCreate(tx *gorm.DB) error {
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{"total": gorm.Expr("total + 1")}),
+	}).Create(&ReactionCount{TargetType: r.TargetType, TargetID: r.TargetID, Kind: r.Kind, Total: 1}).Error
+}
+
+func (r *Reaction) AfterDelete(tx *gorm.DB) error {
+	if !deletedLiveRow(tx, gorm.DeletedAt{}) {
+		return nil
+	}
+	return tx.Model(&ReactionCount{}).
+		Where("target_type = ? AND target_id = ? AND kind = ?", r.TargetType, r.TargetID, r.Kind).
+		Update("total", gorm.Expr("total - 1")).Error
+}
+
+// LoadReactionCounts returns the reaction counts per kind for each of the targets.
+func LoadReactionCounts(db *gorm.DB, targetType string, ids []uint) (map[uint]map[string]int, error) {
+	counts := make(map[uint]map[string]int, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	var rows []ReactionCount
+	if err := db.Where("target_type = ? AND target_id IN ? AND total > 0", targetType, ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if counts[row.TargetID] == nil {
+			counts[row.TargetID] = make(map[string]int)
+		}
+		counts[row.TargetID][row.Kind] = row.Total
+	}
+	return counts, nil
+}
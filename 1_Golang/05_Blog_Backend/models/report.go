@@ -0,0 +1,42 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Report states.
+const (
+	ReportOpen      = "open"
+	ReportActioned  = "actioned"
+	ReportDismissed = "dismissed"
+)
+
+// TargetUser is the report target type of a user account; posts and comments
+// use the reaction target types.
+const TargetUser = "user"
+
+// ReportReasons are the categories a report can be filed under.
+var ReportReasons = []string{"spam", "harassment", "hate", "sexual", "violence", "misinformation", "other"}
+
+// Report flags a post, comment or user for the moderators. A user can report a
+// target once, so the number of reports is the number of distinct reporters.
+type Report struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	ReporterID  uint       `gorm:"uniqueIndex:idx_report_unique;not null" json:"reporter_id"`
+	TargetType  string     `gorm:"type:varchar(20);uniqueIndex:idx_report_unique;index:idx_report_target;not null" json:"target_type"`
+	TargetID    uint       `gorm:"uniqueIndex:idx_report_unique;index:idx_report_target;not null" json:"target_id"`
+	Reason      string     `gorm:"type:varchar(20);not null" json:"reason"`
+	Detail      string     `gorm:"type:varchar(1000)" json:"detail,omitempty"`
+	Status      string     `gorm:"type:varchar(20);index;not null" json:"status"`
+	ModeratorID *uint      `json:"moderator_id,omitempty"`
+	Resolution  string     `gorm:"type:varchar(20)" json:"resolution,omitempty"`
+	Note        string     `gorm:"type:varchar(1000)" json:"note,omitempty"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ValidReportReason reports whether reason is one of ReportReasons.
+func ValidReportReason(reason string) bool {
+	return slices.Contains(ReportReasons, reason)
+}
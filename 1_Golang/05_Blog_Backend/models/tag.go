@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Tag groups posts by topic, e.g. for per-tag feeds. Names are stored lowercase.
+type Tag struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"type:varchar(64);uniqueIndex;not null" json:"name"`
+}
+
+// NormalizeTags lowercases and trims the tag names and drops repeated ones and
+// those that cannot be a URL path segment, as tags are addressed by /tags/{name}.
+func NormalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+	return tags
+}
+
+// SetPostTags replaces the tags of the post, creating tags that do not exist yet.
+func SetPostTags(tx *gorm.DB, post *Post, names []string) error {
+	tags := make([]Tag, 0, len(names))
+	for _, name := range NormalizeTags(names) {
+		tag := Tag{Name: name}
+		if err := tx.Where(Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+		tags = append(tags, tag)
+	}
+	post.Tags = tags
+	return tx.Model(post).Association("Tags").Replace(tags)
+}
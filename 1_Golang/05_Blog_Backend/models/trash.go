@@ -0,0 +1,92 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// TrashPost soft-deletes the post and moves its comments to the trash with it.
+// The comments get the post's exact deletion time, which is how RestorePost
+// tells them apart from comments that were deleted on their own.
+func TrashPost(tx *gorm.DB, post *Post) error {
+	if err := tx.Delete(post).Error; err != nil {
+		return err
+	}
+
+	deletedAt := tx.Session(&gorm.Session{NewDB: true}).Unscoped().
+		Model(&Post{}).Select("deleted_at").Where("id = ?", post.ID)
+	if err := tx.Model(&Comment{}).Where("post_id = ?", post.ID).
+		UpdateColumn("deleted_at", deletedAt).Error; err != nil {
+		return err
+	}
+
+	// the cascaded comments skip the Comment hooks, none of them count anymore
+	return tx.Model(&Post{}).Unscoped().Where("id = ?", post.ID).UpdateColumn("comment_count", 0).Error
+}
+
+// RemovePost trashes the post on behalf of a moderator, recording who removed
+// it so the author cannot restore it.
+func RemovePost(tx *gorm.DB, post *Post, moderatorID uint) error {
+	if err := tx.Model(&Post{}).Where("id = ?", post.ID).UpdateColumn("removed_by", moderatorID).Error; err != nil {
+		return err
+	}
+	post.RemovedBy = &moderatorID
+	return TrashPost(tx, post)
+}
+
+// RemoveComment deletes the comment on behalf of a moderator, recording who
+// removed it so the commenter cannot restore it.
+func RemoveComment(tx *gorm.DB, comment *Comment, moderatorID uint) error {
+	if err := tx.Model(&Comment{}).Where("id = ?", comment.ID).UpdateColumn("removed_by", moderatorID).Error; err != nil {
+		return err
+	}
+	comment.RemovedBy = &moderatorID
+	return tx.Delete(comment).Error
+}
+
+// RestorePost brings a trashed post back together with the comments that were
+// trashed with it. The post must have been loaded with its DeletedAt.
+func RestorePost(tx *gorm.DB, post *Post) error {
+	if !post.DeletedAt.Valid {
+		return nil
+	}
+
+	result := tx.Unscoped().Model(&Post{}).Where("id = ? AND deleted_at IS NOT NULL", post.ID).
+		UpdateColumn("deleted_at", nil)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	if err := tx.Model(&User{}).Where("id = ?", post.UserID).
+		Update("post_count", gorm.Expr("post_count + 1")).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Unscoped().Model(&Comment{}).Where("post_id = ? AND deleted_at = ?", post.ID, post.DeletedAt.Time).
+		UpdateColumn("deleted_at", nil).Error; err != nil {
+		return err
+	}
+
+	liveComments := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&Comment{}).Select("COUNT(*)").Where("post_id = ?", post.ID)
+	if err := tx.Model(&Post{}).Where("id = ?", post.ID).
+		UpdateColumn("comment_count", liveComments).Error; err != nil {
+		return err
+	}
+
+	post.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+// RestoreComment brings a trashed comment back. The caller checks that its post
+// is not in the trash.
+func RestoreComment(tx *gorm.DB, comment *Comment) error {
+	result := tx.Unscoped().Model(&Comment{}).Where("id = ? AND deleted_at IS NOT NULL", comment.ID).
+		UpdateColumn("deleted_at", nil)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	comment.DeletedAt = gorm.DeletedAt{}
+	return tx.Model(&Post{}).Where("id = ?", comment.PostId).
+		Update("comment_count", gorm.Expr("comment_count + 1")).Error
+}
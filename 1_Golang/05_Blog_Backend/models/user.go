@@ -2,18 +2,71 @@ package models
 
 import (
 	"blog-backend/utils"
+	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// User roles.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Account statuses. A suspension lapses at StatusUntil, a ban lasts until it is
+// lifted. An erased account is kept, pseudonymized, so references to it stay valid.
+const (
+	UserActive    = "active"
+	UserSuspended = "suspended"
+	UserBanned    = "banned"
+	UserErased    = "erased"
+)
+
+// Erased accounts are renamed to ErasedUsernamePrefix followed by their ID, at
+// an email address of the ErasedEmailDomain. Neither can be registered.
+const (
+	ErasedUsernamePrefix = "deleted-"
+	ErasedEmailDomain    = "erased.invalid"
+)
+
+// ErasedUsername is the username of the erased account.
+func ErasedUsername(userID uint) string {
+	return fmt.Sprintf("%s%d", ErasedUsernamePrefix, userID)
+}
+
+// ErasedEmail is the email address of the erased account.
+func ErasedEmail(userID uint) string {
+	return fmt.Sprintf("%s%d@%s", ErasedUsernamePrefix, userID, ErasedEmailDomain)
+}
+
+// ReservedForErasure reports whether the username or email has the form of an
+// erased account, compared case-insensitively like the database does.
+func ReservedForErasure(username, email string) bool {
+	return strings.HasPrefix(strings.ToLower(username), ErasedUsernamePrefix) ||
+		strings.HasSuffix(strings.ToLower(email), "@"+ErasedEmailDomain)
+}
+
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Username  string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"username"`
-	Email     string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"type:varchar(255);not null" json:"-"`
-	PostCount int       `json:"post_count"`
-	Posts     []Post    `gorm:"foreignKey:UserID" json:"-"`
-	Comments  []Comment `gorm:"foreignKey:CommenterID" json:"-"`
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Username       string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"username"`
+	Email          string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"-"`
+	Password       string    `gorm:"type:varchar(255);not null" json:"-"`
+	Role           string    `gorm:"type:varchar(20);not null;default:user" json:"role"`
+	PostCount      int       `json:"post_count"`
+	FollowerCount  int       `json:"follower_count"`
+	FollowingCount int       `json:"following_count"`
+	Posts          []Post    `gorm:"foreignKey:UserID" json:"-"`
+	Comments       []Comment `gorm:"foreignKey:CommenterID" json:"-"`
+
+	// the moderation state is not public, only admins are shown it
+	Status                string     `gorm:"type:varchar(20);not null;default:active;index" json:"-"`
+	StatusReason          string     `gorm:"type:varchar(255)" json:"-"`
+	StatusUntil           *time.Time `json:"-"`
+	PasswordResetRequired bool       `gorm:"not null;default:false" json:"-"`
+	SessionsValidAfter    *time.Time `json:"-"`
+	CreatedAt             time.Time  `json:"created_at"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -24,5 +77,43 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	}
 
 	u.Password = hashedPassword
+	if u.Role == "" {
+		u.Role = RoleUser
+	}
+	if u.Status == "" {
+		u.Status = UserActive
+	}
 	return nil
 }
+
+// Blocked reports whether the account is banned or suspended at the given time.
+func (u *User) Blocked(now time.Time) bool {
+	switch u.Status {
+	case UserBanned, UserErased:
+		return true
+	case UserSuspended:
+		return u.StatusUntil == nil || now.Before(*u.StatusUntil)
+	}
+	return false
+}
+
+// BlockedMessage explains to a blocked user why they cannot sign in.
+func (u *User) BlockedMessage() string {
+	if u.Status == UserErased {
+		return "Account has been deleted"
+	}
+	message := "Account is banned"
+	if u.Status == UserSuspended && u.StatusUntil != nil {
+		message = "Account is suspended until " + u.StatusUntil.Format(time.RFC3339)
+	}
+	if u.StatusReason != "" {
+		message = fmt.Sprintf("%s: %s", message, u.StatusReason)
+	}
+	return message
+}
+
+// SessionRevoked reports whether a login token issued at issuedAt was invalidated,
+// for example by a forced password reset. Tokens carry whole seconds.
+func (u *User) SessionRevoked(issuedAt time.Time) bool {
+	return u.SessionsValidAfter != nil && issuedAt.Before(u.SessionsValidAfter.Truncate(time.Second))
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook event types.
+const (
+	WebhookPostCreated    = "post.created"
+	WebhookPostUpdated    = "post.updated"
+	WebhookPostDeleted    = "post.deleted"
+	WebhookCommentCreated = "comment.created"
+)
+
+var WebhookEvents = []string{WebhookPostCreated, WebhookPostUpdated, WebhookPostDeleted, WebhookCommentCreated}
+
+// Webhook delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// Webhook is an endpoint that receives signed event payloads. User webhooks
+// receive events about the owner's posts, global ones (admin only) every event.
+type Webhook struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	UserID    uint           `gorm:"index;not null" json:"user_id"`
+	URL       string         `gorm:"type:varchar(2048);not null" json:"url"`
+	Secret    string         `gorm:"type:varchar(128);not null" json:"-"`
+	Events    string         `gorm:"type:varchar(255);not null" json:"-"`
+	Global    bool           `gorm:"not null;default:false" json:"global"`
+	Active    bool           `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// EventList returns the event types the webhook subscribed to.
+func (w *Webhook) EventList() []string {
+	return strings.Fields(w.Events)
+}
+
+// Wants reports whether the webhook subscribed to the event type.
+func (w *Webhook) Wants(event string) bool {
+	return slices.Contains(w.EventList(), event)
+}
+
+// ValidWebhookEvent reports whether event is one of WebhookEvents.
+func ValidWebhookEvent(event string) bool {
+	return slices.Contains(WebhookEvents, event)
+}
+
+// WebhookDelivery is one event queued for a webhook, together with the outcome
+// of its latest attempt. Pending deliveries are retried with exponential
+// backoff until they succeed or become dead.
+type WebhookDelivery struct {
+	ID        uint `gorm:"primaryKey" json:"id"`
+	WebhookID uint `gorm:"index;uniqueIndex:idx_delivery_job,priority:2;not null" json:"webhook_id"`
+	// JobID is the fan-out job that created the delivery, nil for deliveries
+	// from before it was recorded.
+	JobID   *uint  `gorm:"uniqueIndex:idx_delivery_job,priority:1" json:"-"`
+	Event   string `gorm:"type:varchar(50);not null" json:"event"`
+	Payload string `gorm:"type:mediumtext;not null" json:"payload"`
+	// SubjectID is the user whose content the payload copies.
+	SubjectID      uint       `gorm:"index" json:"-"`
+	Status         string     `gorm:"type:varchar(20);index:idx_delivery_due;not null" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_delivery_due" json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `gorm:"type:varchar(1024)" json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -1,17 +1,73 @@
 package routes
 
 import (
+	"blog-backend/cache"
+	"blog-backend/config"
 	"blog-backend/handlers"
 	"blog-backend/middleware"
+	"blog-backend/models"
+	"blog-backend/services"
+	"blog-backend/storage"
+	"blog-backend/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(routes *gin.Engine, db *gorm.DB) {
+// Dependencies are the shared components the handlers are built from.
+type Dependencies struct {
+	DB       *gorm.DB
+	Config   *config.Config
+	Storage  storage.Storage
+	Hub      *services.Hub
+	Webhooks *services.WebhookService
+	Cache    *cache.Store
+	Views    *services.ViewTracker
+	Accounts *services.AccountService
+}
+
+func SetupRoutes(routes *gin.Engine, deps *Dependencies) {
+	db, cfg, hub := deps.DB, deps.Config, deps.Hub
+	notifier := &services.NotificationService{DB: db, Hub: hub}
+
 	AuthHandler := &handlers.AuthHandler{DB: db}
-	PostHandler := &handlers.PostHandler{DB: db}
-	CommentHandler := &handlers.CommentHandler{DB: db}
+	OIDCHandler := &handlers.OIDCHandler{DB: db}
+	if cfg.OIDCEnabled() {
+		OIDCHandler.Provider = utils.NewOIDCProvider(cfg.OIDCIssuer, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL)
+	}
+	filter := services.NewContentPipeline(db, cfg.BannedWords, int(cfg.MaxLinks), time.Duration(cfg.NewAccountHours)*time.Hour)
+
+	reader := &services.PostReader{DB: db, Cache: deps.Cache}
+
+	PostHandler := &handlers.PostHandler{DB: db, Hub: hub, Webhooks: deps.Webhooks, Filter: filter, Posts: reader,
+		Views: deps.Views, AuthorScopedSlugs: cfg.SlugScope == "author"}
+	CommentHandler := &handlers.CommentHandler{DB: db, Notifier: notifier, Hub: hub, Webhooks: deps.Webhooks, Filter: filter}
+	TokenHandler := &handlers.TokenHandler{DB: db}
+	AdminHandler := &handlers.AdminHandler{DB: db, Webhooks: deps.Webhooks, AuthorScopedSlugs: cfg.SlugScope == "author"}
+	UserHandler := &handlers.UserHandler{DB: db, Feed: &services.FanOutOnReadFeed{DB: db}, Notifier: notifier, Cache: deps.Cache}
+	ReactionHandler := &handlers.ReactionHandler{DB: db, Notifier: notifier}
+	NotificationHandler := &handlers.NotificationHandler{DB: db, Notifier: notifier}
+	StreamHandler := &handlers.StreamHandler{DB: db, Hub: hub}
+	BookmarkHandler := &handlers.BookmarkHandler{DB: db}
+	WebhookHandler := &handlers.WebhookHandler{DB: db, Webhooks: deps.Webhooks}
+	ReportHandler := &handlers.ReportHandler{
+		DB:            db,
+		Notifier:      notifier,
+		Webhooks:      deps.Webhooks,
+		Hub:           hub,
+		HideThreshold: int(cfg.ReportHideThreshold),
+	}
+	SyndicationHandler := &handlers.SyndicationHandler{DB: db, Posts: reader, SiteURL: cfg.SiteURL, SiteTitle: cfg.SiteTitle}
+	AnalyticsHandler := &handlers.AnalyticsHandler{DB: db, Posts: reader}
+	AccountHandler := &handlers.AccountHandler{DB: db, Storage: deps.Storage, Accounts: deps.Accounts}
+	TrashHandler := &handlers.TrashHandler{DB: db, Retention: cfg.TrashRetention()}
+	AttachmentHandler := &handlers.AttachmentHandler{
+		DB:            db,
+		Storage:       deps.Storage,
+		MaxImageBytes: cfg.MaxImageBytes,
+		MaxFileBytes:  cfg.MaxFileBytes,
+	}
 
 	api := routes.Group("/api")
 	{
@@ -19,20 +75,127 @@ func SetupRoutes(routes *gin.Engine, db *gorm.DB) {
 		{
 			auth.POST("/register", AuthHandler.Register)
 			auth.POST("/login", AuthHandler.Login)
+			auth.POST("/password-reset", AuthHandler.ResetPassword)
+			auth.GET("/oidc/login", OIDCHandler.OIDCLogin)
+			auth.GET("/oidc/callback", OIDCHandler.OIDCCallback)
+		}
+
+		streams := api.Group("")
+		streams.Use(middleware.QueryToken(), middleware.AuthMiddleware(db))
+		{
+			streams.GET("/posts/:post_id/events", StreamHandler.PostEvents)
+			streams.GET("/notifications/socket", middleware.RequireScope(models.ScopeNotificationsRead), StreamHandler.NotificationSocket)
 		}
 
 		authenticated := api.Group("")
-		authenticated.Use(middleware.AuthMiddleware())
+		authenticated.Use(middleware.AuthMiddleware(db))
 		{
 			posts := authenticated.Group("/posts")
+			posts.Use(middleware.RequireScope(models.ScopePostsWrite))
 			{
 				posts.POST("", PostHandler.CreatePost)
 				posts.PUT("/:post_id", PostHandler.UpdatePost)
 				posts.DELETE("/:post_id", PostHandler.DeletePost)
+				posts.POST("/:post_id/restore", TrashHandler.RestorePost)
+				posts.POST("/:post_id/revisions/:revision/restore", PostHandler.RestoreRevision)
+				posts.POST("/:post_id/images", AttachmentHandler.UploadImage)
+				posts.POST("/:post_id/attachments", AttachmentHandler.UploadFile)
+				posts.DELETE("/:post_id/attachments/:attachment_id", AttachmentHandler.DeleteAttachment)
 			}
 			comments := authenticated.Group("/posts/:post_id/comments")
+			comments.Use(middleware.RequireScope(models.ScopeCommentsWrite))
 			{
 				comments.POST("", CommentHandler.CreateComment)
+				comments.PUT("/:comment_id", CommentHandler.UpdateComment)
+				comments.DELETE("/:comment_id", CommentHandler.DeleteComment)
+				comments.POST("/:comment_id/restore", TrashHandler.RestoreComment)
+			}
+			reactions := authenticated.Group("/posts/:post_id")
+			reactions.Use(middleware.RequireScope(models.ScopeReactionsWrite))
+			{
+				reactions.PUT("/reactions/:kind", ReactionHandler.ReactToPost)
+				reactions.DELETE("/reactions/:kind", ReactionHandler.UnreactToPost)
+				reactions.PUT("/comments/:comment_id/reactions/:kind", ReactionHandler.ReactToComment)
+				reactions.DELETE("/comments/:comment_id/reactions/:kind", ReactionHandler.UnreactToComment)
+			}
+			bookmarks := authenticated.Group("")
+			bookmarks.Use(middleware.RequireScope(models.ScopeBookmarksWrite))
+			{
+				bookmarks.PUT("/posts/:post_id/bookmark", BookmarkHandler.AddBookmark)
+				bookmarks.DELETE("/posts/:post_id/bookmark", BookmarkHandler.RemoveBookmark)
+			}
+			personal := authenticated.Group("")
+			personal.Use(middleware.RequireScope(models.ScopeAccountRead))
+			{
+				personal.GET("/me/bookmarks", BookmarkHandler.ListBookmarks)
+				personal.GET("/me/trash", TrashHandler.ListTrash)
+				personal.GET("/me/analytics", AnalyticsHandler.GetMyAnalytics)
+				personal.GET("/feed", UserHandler.GetFeed)
+			}
+			account := authenticated.Group("/me")
+			account.Use(middleware.RequireSession())
+			{
+				account.GET("/export", AccountHandler.GetDataExport)
+				account.POST("/erasure", AccountHandler.EraseAccount)
+			}
+			reports := authenticated.Group("")
+			reports.Use(middleware.RequireScope(models.ScopeReportsWrite))
+			{
+				reports.POST("/posts/:post_id/report", ReportHandler.ReportPost)
+				reports.POST("/posts/:post_id/comments/:comment_id/report", ReportHandler.ReportComment)
+				reports.POST("/users/:user_id/report", ReportHandler.ReportUser)
+			}
+			follows := authenticated.Group("/users/:user_id/follow")
+			follows.Use(middleware.RequireScope(models.ScopeFollowsWrite))
+			{
+				follows.PUT("", UserHandler.Follow)
+				follows.DELETE("", UserHandler.Unfollow)
+			}
+			notifications := authenticated.Group("")
+			notifications.Use(middleware.RequireScope(models.ScopeNotificationsRead))
+			{
+				notifications.GET("/notifications", NotificationHandler.ListNotifications)
+				notifications.GET("/me/notification-preferences", NotificationHandler.GetPreferences)
+			}
+			notificationUpdates := authenticated.Group("")
+			notificationUpdates.Use(middleware.RequireScope(models.ScopeNotificationsWrite))
+			{
+				notificationUpdates.POST("/notifications/:notification_id/read", NotificationHandler.MarkNotificationRead)
+				notificationUpdates.POST("/notifications/read-all", NotificationHandler.MarkAllNotificationsRead)
+				notificationUpdates.PUT("/me/notification-preferences", NotificationHandler.UpdatePreferences)
+			}
+			webhooks := authenticated.Group("/webhooks")
+			webhooks.Use(middleware.RequireSession())
+			{
+				webhooks.POST("", WebhookHandler.CreateWebhook)
+				webhooks.GET("", WebhookHandler.ListWebhooks)
+				webhooks.DELETE("/:webhook_id", WebhookHandler.DeleteWebhook)
+				webhooks.GET("/:webhook_id/deliveries", WebhookHandler.ListDeliveries)
+				webhooks.POST("/:webhook_id/deliveries/:delivery_id/redeliver", WebhookHandler.RedeliverDelivery)
+			}
+			tokens := authenticated.Group("/me/tokens")
+			tokens.Use(middleware.RequireSession())
+			{
+				tokens.POST("", TokenHandler.CreateToken)
+				tokens.GET("", TokenHandler.ListTokens)
+				tokens.DELETE("/:token_id", TokenHandler.RevokeToken)
+			}
+			admin := authenticated.Group("/admin")
+			admin.Use(middleware.RequireSession(), middleware.AdminMiddleware(db))
+			{
+				admin.GET("/users", AdminHandler.SearchUsers)
+				admin.PUT("/users/:user_id/role", AdminHandler.ChangeUserRole)
+				admin.PUT("/users/:user_id/status", AdminHandler.ChangeUserStatus)
+				admin.POST("/users/:user_id/password-reset", AdminHandler.ForcePasswordReset)
+				admin.POST("/posts/bulk-delete", AdminHandler.BulkDeletePosts)
+				admin.POST("/comments/bulk-delete", AdminHandler.BulkDeleteComments)
+				admin.POST("/posts/import", AdminHandler.ImportPosts)
+				admin.GET("/stats", AdminHandler.GetStats)
+				admin.GET("/reports", ReportHandler.ListReports)
+				admin.POST("/reports/:report_id/resolve", ReportHandler.ResolveReport)
+				admin.GET("/audit-events", AdminHandler.ListAuditEvents)
+				admin.GET("/jobs", AdminHandler.ListJobs)
+				admin.POST("/jobs/:job_id/retry", AdminHandler.RetryJob)
 			}
 		}
 
@@ -40,8 +203,26 @@ func SetupRoutes(routes *gin.Engine, db *gorm.DB) {
 		{
 			posts := public.Group("/posts")
 			{
-				posts.GET("/:post_id", PostHandler.GetPost)
+				// signed-in readers are told apart for view counting
+				posts.GET("/:post_id", middleware.OptionalAuth(db), PostHandler.GetPost)
+				posts.GET("/by-slug/:slug", middleware.OptionalAuth(db), PostHandler.GetPostBySlug)
 				posts.GET("", PostHandler.GetAllPosts)
+				posts.GET("/trending", AnalyticsHandler.GetTrendingPosts)
+				posts.GET("/:post_id/revisions", PostHandler.ListRevisions)
+				posts.GET("/:post_id/revisions/diff", PostHandler.DiffRevisions)
+				posts.GET("/:post_id/attachments", AttachmentHandler.ListAttachments)
+			}
+			users := public.Group("/users/:user_id")
+			{
+				users.GET("", UserHandler.GetProfile)
+				users.GET("/followers", UserHandler.ListFollowers)
+				users.GET("/following", UserHandler.ListFollowing)
+				users.GET("/posts/by-slug/:slug", middleware.OptionalAuth(db), PostHandler.GetUserPostBySlug)
+			}
+			attachments := public.Group("/attachments")
+			{
+				attachments.GET("/:attachment_id", AttachmentHandler.DownloadAttachment)
+				attachments.GET("/:attachment_id/thumbnail", AttachmentHandler.DownloadThumbnail)
 			}
 			comments := public.Group("/posts/:post_id/comments")
 			{
@@ -56,4 +237,12 @@ func SetupRoutes(routes *gin.Engine, db *gorm.DB) {
 			"message": "Blog API is running",
 		})
 	})
+
+	feeds := routes.Group("")
+	feeds.GET("/sitemap.xml", SyndicationHandler.Sitemap)
+	for format := range services.FeedContentTypes {
+		feeds.GET("/feed."+format, SyndicationHandler.SiteFeed)
+		feeds.GET("/users/:user_id/feed."+format, SyndicationHandler.AuthorFeed)
+		feeds.GET("/tags/:tag/feed."+format, SyndicationHandler.TagFeed)
+	}
 }
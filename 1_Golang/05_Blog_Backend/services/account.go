@@ -0,0 +1,182 @@
+package services
+
+import (
+	"blog-backend/models"
+	"blog-backend/storage"
+	"blog-backend/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Job types of AccountService.
+const (
+	JobBuildDataExport  = "account.export"
+	JobPurgeDataExports = "account.purge_exports"
+	JobEraseAccount     = "account.erase"
+)
+
+// DataExportPurgeInterval is how often expired data exports are removed.
+const DataExportPurgeInterval = time.Hour
+
+// AccountService builds personal data exports and erases accounts, both in
+// background jobs, whose handlers are registered on Jobs.
+type AccountService struct {
+	DB      *gorm.DB
+	Storage storage.Storage
+	Jobs    *JobQueue
+}
+
+func NewAccountService(db *gorm.DB, store storage.Storage, jobs *JobQueue) *AccountService {
+	s := &AccountService{DB: db, Storage: store, Jobs: jobs}
+	jobs.Register(JobBuildDataExport, s.buildExport)
+	jobs.Register(JobPurgeDataExports, s.purgeExports)
+	jobs.Register(JobEraseAccount, s.erase)
+	return s
+}
+
+type dataExportJob struct {
+	ExportID uint `json:"export_id"`
+}
+
+type eraseAccountJob struct {
+	UserID uint `json:"user_id"`
+}
+
+// RequestExport starts building an archive of the user's data.
+func (s *AccountService) RequestExport(userID uint) (*models.DataExport, error) {
+	export := models.DataExport{UserID: userID, Status: models.ExportPending}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&export).Error; err != nil {
+			return err
+		}
+		return s.Jobs.Enqueue(tx, JobBuildDataExport, dataExportJob{ExportID: export.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// RequestErasure blocks the account and revokes its sessions and tokens right
+// away, and erases it in the background.
+func (s *AccountService) RequestErasure(userID uint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
+			"status":               models.UserErased,
+			"status_reason":        "",
+			"status_until":         nil,
+			"sessions_valid_after": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.APIToken{}).Error; err != nil {
+			return err
+		}
+		return s.Jobs.Enqueue(tx, JobEraseAccount, eraseAccountJob{UserID: userID})
+	})
+}
+
+func (s *AccountService) buildExport(ctx context.Context, payload []byte) error {
+	var job dataExportJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+
+	var export models.DataExport
+	if err := s.DB.WithContext(ctx).First(&export, job.ExportID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// purged, or the account was erased meanwhile
+			return nil
+		}
+		return err
+	}
+	if export.Status != models.ExportPending {
+		return nil
+	}
+
+	file, err := os.CreateTemp("", "data-export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := WriteDataExport(s.DB.WithContext(ctx), export.UserID, file); err != nil {
+		return err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	suffix, err := utils.RandomString(8)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("exports/%d/%d-%s.zip", export.UserID, export.ID, suffix)
+	if err := s.Storage.Put(ctx, key, file, size, "application/zip"); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(models.DataExportTTL)
+	result := s.DB.WithContext(ctx).Model(&export).Where("status = ?", models.ExportPending).Updates(map[string]any{
+		"status":      models.ExportReady,
+		"storage_key": key,
+		"size":        size,
+		"expires_at":  expiresAt,
+	})
+	if result.Error != nil || result.RowsAffected == 0 {
+		deleteObjects(ctx, s.Storage, []string{key})
+	}
+	return result.Error
+}
+
+// purgeExports removes expired exports and exports given up as stale.
+func (s *AccountService) purgeExports(ctx context.Context, payload []byte) error {
+	now := time.Now()
+	var exports []models.DataExport
+	if err := s.DB.WithContext(ctx).
+		Where("expires_at < ? OR (status = ? AND created_at < ?)", now, models.ExportPending, now.Add(-models.DataExportStale)).
+		Find(&exports).Error; err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if err := s.DB.WithContext(ctx).Delete(&export).Error; err != nil {
+			return err
+		}
+		if export.StorageKey != "" {
+			deleteObjects(ctx, s.Storage, []string{export.StorageKey})
+		}
+	}
+	if len(exports) > 0 {
+		log.Printf("Purged %d data exports", len(exports))
+	}
+	return nil
+}
+
+func (s *AccountService) erase(ctx context.Context, payload []byte) error {
+	var job eraseAccountJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+
+	keys, err := EraseAccount(s.DB.WithContext(ctx), job.UserID)
+	deleteObjects(ctx, s.Storage, keys)
+	if err != nil {
+		return err
+	}
+	log.Printf("Erased account %d", job.UserID)
+	return nil
+}
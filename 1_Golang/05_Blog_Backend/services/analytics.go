@@ -0,0 +1,243 @@
+package services
+
+import (
+	"blog-backend/cache"
+	"blog-backend/models"
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// trendingDays is how far back activity counts towards trending.
+	trendingDays = 7
+	// trendingHalfLife is after how long activity counts half as much.
+	trendingHalfLife = 24 * time.Hour
+	// Weights of a comment and a reaction relative to a view.
+	trendingCommentWeight  = 5
+	trendingReactionWeight = 2
+)
+
+// postDay is the activity on a post during one day.
+type postDay struct {
+	PostID    uint
+	Day       time.Time
+	Views     int64
+	Comments  int64
+	Reactions int64
+}
+
+// postActivity returns the views, comments and reactions per post and day
+// since the start of the since day, of the posts selected by scope, which
+// filters on the joined posts table.
+func postActivity(db *gorm.DB, since time.Time, scope PostScope) ([]postDay, error) {
+	type row struct {
+		PostID uint
+		Day    time.Time
+		Total  int64
+	}
+
+	days := map[[2]int64]*postDay{}
+	var order []*postDay
+	for _, series := range []struct {
+		query func() *gorm.DB
+		field func(*postDay) *int64
+	}{
+		{func() *gorm.DB {
+			return db.Table("post_daily_views").
+				Select("post_daily_views.post_id, post_daily_views.day, post_daily_views.views AS total").
+				Joins("JOIN posts ON posts.id = post_daily_views.post_id AND posts.deleted_at IS NULL").
+				Where("post_daily_views.day >= ?", since)
+		}, func(d *postDay) *int64 { return &d.Views }},
+		{func() *gorm.DB {
+			return db.Table("comments").
+				Select("comments.post_id, DATE(comments.created_at) AS day, COUNT(*) AS total").
+				Joins("JOIN posts ON posts.id = comments.post_id AND posts.deleted_at IS NULL").
+				Where("comments.deleted_at IS NULL AND comments.created_at >= ?", since).
+				Group("comments.post_id, DATE(comments.created_at)")
+		}, func(d *postDay) *int64 { return &d.Comments }},
+		{func() *gorm.DB {
+			return db.Table("reactions").
+				Select("reactions.target_id AS post_id, DATE(reactions.created_at) AS day, COUNT(*) AS total").
+				Joins("JOIN posts ON posts.id = reactions.target_id AND posts.deleted_at IS NULL").
+				Where("reactions.target_type = ? AND reactions.created_at >= ?", models.TargetPost, since).
+				Group("reactions.target_id, DATE(reactions.created_at)")
+		}, func(d *postDay) *int64 { return &d.Reactions }},
+	} {
+		var rows []row
+		if err := series.query().Scopes(scope).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			day := time.Date(r.Day.Year(), r.Day.Month(), r.Day.Day(), 0, 0, 0, 0, time.Local)
+			key := [2]int64{int64(r.PostID), day.Unix()}
+			if days[key] == nil {
+				days[key] = &postDay{PostID: r.PostID, Day: day}
+				order = append(order, days[key])
+			}
+			*series.field(days[key]) += r.Total
+		}
+	}
+
+	activity := make([]postDay, len(order))
+	for i, d := range order {
+		activity[i] = *d
+	}
+	return activity, nil
+}
+
+// Trending returns up to limit published posts ranked by their activity of the
+// last week. Views, comments and reactions lose half their weight every
+// trendingHalfLife, so a burst of activity today outranks a larger one last week.
+func (r *PostReader) Trending(limit int) ([]models.Post, error) {
+	now := time.Now()
+	activity, err := postActivity(r.DB, today().AddDate(0, 0, -(trendingDays-1)), models.VisiblePosts)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := map[uint]float64{}
+	for _, d := range activity {
+		// a day's activity is taken to have happened at its noon
+		age := max(now.Sub(d.Day.Add(12*time.Hour)), 0)
+		weight := math.Exp2(-float64(age) / float64(trendingHalfLife))
+		scores[d.PostID] += weight * float64(d.Views+trendingCommentWeight*d.Comments+trendingReactionWeight*d.Reactions)
+	}
+
+	ids := sortedByScore(scores)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	if len(ids) == 0 {
+		return []models.Post{}, nil
+	}
+
+	posts, err := r.List(func(db *gorm.DB) *gorm.DB { return db.Where("posts.id IN ?", ids) })
+	if err != nil {
+		return nil, err
+	}
+	rank := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		rank[id] = i
+	}
+	sort.Slice(posts, func(i, j int) bool { return rank[posts[i].ID] < rank[posts[j].ID] })
+	return posts, nil
+}
+
+// CachedTrending is Trending through the cache. View counts do not invalidate
+// the cache, so the ranking is recomputed at most once per cache TTL.
+func (r *PostReader) CachedTrending(ctx context.Context, limit int) ([]models.Post, error) {
+	return cache.Fetch(ctx, r.Cache, r.Cache.Key(ctx, CachePosts, "trending", limit), func() ([]models.Post, error) {
+		return r.Trending(limit)
+	})
+}
+
+// sortedByScore returns the keys by descending score, newer posts first on ties.
+func sortedByScore(scores map[uint]float64) []uint {
+	ids := make([]uint, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] > ids[j]
+	})
+	return ids
+}
+
+// DailyActivity is the activity on an author's posts during one day.
+type DailyActivity struct {
+	Date      string `json:"date"`
+	Views     int64  `json:"views"`
+	Comments  int64  `json:"comments"`
+	Reactions int64  `json:"reactions"`
+}
+
+// PostActivity is the activity on one post during the analytics period.
+type PostActivity struct {
+	PostID    uint   `json:"post_id"`
+	Title     string `json:"title"`
+	Slug      string `json:"slug"`
+	Views     int64  `json:"views"`
+	Comments  int64  `json:"comments"`
+	Reactions int64  `json:"reactions"`
+	// TotalViews counts the views since the post was published.
+	TotalViews int64 `json:"total_views"`
+}
+
+// AuthorAnalytics is the activity on an author's posts over the last days,
+// hidden posts included.
+type AuthorAnalytics struct {
+	Days      int             `json:"days"`
+	Views     int64           `json:"views"`
+	Comments  int64           `json:"comments"`
+	Reactions int64           `json:"reactions"`
+	Daily     []DailyActivity `json:"daily"`
+	Posts     []PostActivity  `json:"posts"`
+}
+
+// LoadAuthorAnalytics sums up the activity on the author's posts per day, one
+// entry per day oldest first, and per post, most viewed first.
+func LoadAuthorAnalytics(db *gorm.DB, userID uint, days int) (*AuthorAnalytics, error) {
+	since := today().AddDate(0, 0, -(days - 1))
+	activity, err := postActivity(db, since, ByAuthor(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := &AuthorAnalytics{Days: days, Daily: make([]DailyActivity, days), Posts: []PostActivity{}}
+	index := make(map[string]*DailyActivity, days)
+	for i := range analytics.Daily {
+		analytics.Daily[i].Date = since.AddDate(0, 0, i).Format(time.DateOnly)
+		index[analytics.Daily[i].Date] = &analytics.Daily[i]
+	}
+
+	perPost := map[uint]*PostActivity{}
+	var ids []uint
+	for _, d := range activity {
+		if day, ok := index[d.Day.Format(time.DateOnly)]; ok {
+			day.Views += d.Views
+			day.Comments += d.Comments
+			day.Reactions += d.Reactions
+		}
+		if perPost[d.PostID] == nil {
+			perPost[d.PostID] = &PostActivity{PostID: d.PostID}
+			ids = append(ids, d.PostID)
+		}
+		post := perPost[d.PostID]
+		post.Views += d.Views
+		post.Comments += d.Comments
+		post.Reactions += d.Reactions
+
+		analytics.Views += d.Views
+		analytics.Comments += d.Comments
+		analytics.Reactions += d.Reactions
+	}
+
+	if len(ids) > 0 {
+		var posts []models.Post
+		if err := db.Select("id", "title", "slug", "view_count").Where("id IN ?", ids).Find(&posts).Error; err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			perPost[post.ID].Title, perPost[post.ID].Slug = post.Title, post.Slug
+			perPost[post.ID].TotalViews = post.ViewCount
+		}
+	}
+	for _, id := range ids {
+		analytics.Posts = append(analytics.Posts, *perPost[id])
+	}
+	sort.Slice(analytics.Posts, func(i, j int) bool {
+		a, b := analytics.Posts[i], analytics.Posts[j]
+		if a.Views != b.Views {
+			return a.Views > b.Views
+		}
+		return a.PostID > b.PostID
+	})
+	return analytics, nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"blog-backend/models"
+	"blog-backend/utils"
+	"encoding/json"
+	"log"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Change is the old and new value of a single field.
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// Changes maps field names to their changes, it is stored as the audit diff.
+type Changes map[string]Change
+
+// Add records the field change if the value actually changed.
+func (c Changes) Add(field string, from, to any) Changes {
+	if !reflect.DeepEqual(from, to) {
+		c[field] = Change{From: from, To: to}
+	}
+	return c
+}
+
+// RecordAudit writes an audit event for the request. The actor is taken from the
+// authenticated user, if any. Failures are logged and never fail the request.
+func RecordAudit(db *gorm.DB, c *gin.Context, action, targetType string, targetID uint, diff any) {
+	var actorID *uint
+	if userID, exists := c.Get("userID"); exists {
+		id := userID.(uint)
+		actorID = &id
+	}
+
+	recordAudit(db, c, actorID, action, targetType, targetID, diff)
+}
+
+// RecordAuditAs writes an audit event with an explicit actor, for requests that
+// are not authenticated yet such as register and login.
+func RecordAuditAs(db *gorm.DB, c *gin.Context, actorID uint, action, targetType string, targetID uint, diff any) {
+	recordAudit(db, c, &actorID, action, targetType, targetID, diff)
+}
+
+func recordAudit(db *gorm.DB, c *gin.Context, actorID *uint, action, targetType string, targetID uint, diff any) {
+	event := models.AuditEvent{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         c.ClientIP(),
+		UserAgent:  utils.Truncate(c.Request.UserAgent(), 255),
+	}
+
+	if diff != nil {
+		data, err := json.Marshal(diff)
+		if err != nil {
+			log.Printf("Failed to encode audit diff: %v", err)
+		} else {
+			event.Diff = string(data)
+		}
+	}
+
+	if err := db.Create(&event).Error; err != nil {
+		log.Printf("Failed to record audit event %s: %v", action, err)
+	}
+}
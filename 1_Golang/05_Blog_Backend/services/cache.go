@@ -0,0 +1,69 @@
+package services
+
+import (
+	"blog-backend/cache"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Cache namespaces. Each is invalidated as a whole when a table it is built
+// from is written.
+const (
+	// CachePosts holds post details, post lists and feeds.
+	CachePosts = "posts"
+	// CacheUsers holds user profiles.
+	CacheUsers = "users"
+)
+
+// cacheTables maps tables to the namespaces that read from them. Authors are
+// embedded in posts, so user changes also invalidate the posts.
+var cacheTables = map[string][]string{
+	"posts":     {CachePosts},
+	"comments":  {CachePosts},
+	"tags":      {CachePosts},
+	"post_tags": {CachePosts},
+	"users":     {CachePosts, CacheUsers},
+	"follows":   {CacheUsers},
+}
+
+// SkipCacheInvalidation can be set on a statement, with db.Set, for writes
+// whose cached copies may stay stale until they expire, such as view counts.
+const SkipCacheInvalidation = "cache:skip_invalidation"
+
+// invalidationDelay is when a written table's namespaces are invalidated a
+// second time, as a read racing the writing transaction may have cached the
+// data as it was before the commit.
+const invalidationDelay = time.Second
+
+// RegisterCacheInvalidation invalidates the cached namespaces of every table
+// written through db, whichever handler, job or command writes it.
+func RegisterCacheInvalidation(db *gorm.DB, store *cache.Store) error {
+	invalidate := func(tx *gorm.DB) {
+		if tx.Error != nil || tx.RowsAffected == 0 {
+			return
+		}
+		if skip, _ := tx.Get(SkipCacheInvalidation); skip == true {
+			return
+		}
+		namespaces := cacheTables[tx.Statement.Table]
+		if len(namespaces) == 0 {
+			return
+		}
+
+		store.Invalidate(context.Background(), namespaces...)
+		time.AfterFunc(invalidationDelay, func() {
+			store.Invalidate(context.Background(), namespaces...)
+		})
+	}
+
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("cache:invalidate", invalidate); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("cache:invalidate", invalidate); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Register("cache:invalidate", invalidate)
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"blog-backend/models"
+	"blog-backend/utils"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// Content filter verdicts.
+const (
+	VerdictAccept = "accept"
+	VerdictHold   = "hold"
+	VerdictReject = "reject"
+)
+
+// Submission is a post or comment about to be saved.
+type Submission struct {
+	TargetType string
+	// TargetID is zero for new content.
+	TargetID uint
+	Author   models.User
+	Title    string
+	Content  string
+}
+
+// Text is everything a filter should read.
+func (s *Submission) Text() string {
+	if s.Title == "" {
+		return s.Content
+	}
+	return s.Title + "\n" + s.Content
+}
+
+// FilterResult is one filter's opinion. Scores of all filters are added up,
+// Reject refuses the submission regardless of the total.
+type FilterResult struct {
+	Score  float64
+	Reject bool
+	Reason string
+}
+
+// ContentFilter inspects a submission before it is saved.
+type ContentFilter interface {
+	Check(ctx context.Context, s *Submission) (FilterResult, error)
+}
+
+// Decision is the outcome of a ContentPipeline.
+type Decision struct {
+	Verdict string
+	Score   float64
+	Reasons []string
+}
+
+// ContentPipeline runs every filter and turns the summed score into a verdict:
+// accept below HoldScore, hold for moderation below RejectScore, reject above.
+type ContentPipeline struct {
+	Filters     []ContentFilter
+	HoldScore   float64
+	RejectScore float64
+}
+
+// NewContentPipeline builds the pipeline of built-in filters. An extra link or a
+// new account alone is fine, together or with a duplicate they hold the content.
+func NewContentPipeline(db *gorm.DB, bannedWords []string, maxLinks int, newAccountAge time.Duration) *ContentPipeline {
+	return &ContentPipeline{
+		Filters: []ContentFilter{
+			NewBannedWordsFilter(bannedWords),
+			&LinkLimitFilter{MaxLinks: maxLinks, ScorePerLink: 0.5},
+			&DuplicateFilter{DB: db, Window: 24 * time.Hour, Score: 1},
+			&NewAccountFilter{DB: db, MinAge: newAccountAge, MaxPerHour: 5, Score: 0.5},
+		},
+		HoldScore:   1,
+		RejectScore: 3,
+	}
+}
+
+// Evaluate runs the filters. A nil pipeline accepts everything.
+func (p *ContentPipeline) Evaluate(ctx context.Context, s *Submission) (Decision, error) {
+	decision := Decision{Verdict: VerdictAccept}
+	if p == nil {
+		return decision, nil
+	}
+
+	rejected := false
+	for _, filter := range p.Filters {
+		result, err := filter.Check(ctx, s)
+		if err != nil {
+			return decision, err
+		}
+		if result.Score == 0 && !result.Reject {
+			continue
+		}
+		decision.Score += result.Score
+		decision.Reasons = append(decision.Reasons, result.Reason)
+		rejected = rejected || result.Reject
+	}
+
+	switch {
+	case rejected || decision.Score >= p.RejectScore:
+		decision.Verdict = VerdictReject
+	case decision.Score >= p.HoldScore:
+		decision.Verdict = VerdictHold
+	}
+	return decision, nil
+}
+
+// HoldContent files a report without a reporter for content the pipeline held,
+// so it shows up in the moderation queue. The content itself is saved hidden.
+func HoldContent(tx *gorm.DB, targetType string, targetID uint, decision Decision) error {
+	detail := "Held by content filter: " + strings.Join(decision.Reasons, "; ")
+	return tx.Where(map[string]any{"reporter_id": 0, "target_type": targetType, "target_id": targetID}).
+		Assign(models.Report{Reason: "spam", Detail: utils.Truncate(detail, 1000), Status: models.ReportOpen}).
+		FirstOrCreate(&models.Report{}).Error
+}
+
+// NormalizeText folds text for matching: compatibility forms (full-width
+// letters), case and diacritics are removed, and common letter substitutions
+// such as "5" for "s" are undone.
+func NormalizeText(text string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), text)
+	if err != nil {
+		folded = text
+	}
+	return leetReplacer.Replace(strings.ToLower(folded))
+}
+
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s")
+
+// BannedWordsFilter rejects content containing a banned word or phrase. Words
+// must match whole words, so "class" does not match "ass", and the words
+// of a phrase match whatever spacing and punctuation separate them; words in
+// scripts written without spaces, such as Chinese, match anywhere.
+type BannedWordsFilter struct {
+	words []string
+}
+
+func NewBannedWordsFilter(words []string) *BannedWordsFilter {
+	f := &BannedWordsFilter{}
+	for _, word := range words {
+		if word = strings.Join(wordTokens(NormalizeText(word)), " "); word != "" {
+			f.words = append(f.words, word)
+		}
+	}
+	return f
+}
+
+func (f *BannedWordsFilter) Check(ctx context.Context, s *Submission) (FilterResult, error) {
+	text := NormalizeText(s.Text())
+	// padded with spaces, so a phrase is found as " phrase " at word boundaries
+	words := " " + strings.Join(wordTokens(text), " ") + " "
+
+	for _, word := range f.words {
+		if strings.Contains(words, " "+word+" ") || (spaceless(word) && strings.Contains(text, word)) {
+			return FilterResult{Reject: true, Reason: "contains a banned word"}, nil
+		}
+	}
+	return FilterResult{}, nil
+}
+
+// wordTokens splits text into its words, dropping spaces and punctuation.
+func wordTokens(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
+
+// spacelessScripts are the scripts written without spaces between words.
+var spacelessScripts = []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai, unicode.Lao,
+	unicode.Khmer, unicode.Myanmar, unicode.Tibetan}
+
+// spaceless reports whether the word is written in a script without spaces,
+// where it cannot be matched as a whole word.
+func spaceless(word string) bool {
+	for _, r := range word {
+		if unicode.In(r, spacelessScripts...) {
+			return true
+		}
+	}
+	return false
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)`)
+
+// LinkLimitFilter scores content with more than MaxLinks links, ScorePerLink
+// for every link above the limit.
+type LinkLimitFilter struct {
+	MaxLinks     int
+	ScorePerLink float64
+}
+
+func (f *LinkLimitFilter) Check(ctx context.Context, s *Submission) (FilterResult, error) {
+	links := len(linkPattern.FindAllStringIndex(s.Text(), -1))
+	if links <= f.MaxLinks {
+		return FilterResult{}, nil
+	}
+	return FilterResult{
+		Score:  float64(links-f.MaxLinks) * f.ScorePerLink,
+		Reason: fmt.Sprintf("%d links, at most %d allowed", links, f.MaxLinks),
+	}, nil
+}
+
+// DuplicateFilter scores content the author already posted within Window,
+// which catches comment flooding and copy-pasted spam.
+type DuplicateFilter struct {
+	DB     *gorm.DB
+	Window time.Duration
+	Score  float64
+}
+
+func (f *DuplicateFilter) Check(ctx context.Context, s *Submission) (FilterResult, error) {
+	var query *gorm.DB
+	switch s.TargetType {
+	case models.TargetPost:
+		query = f.DB.WithContext(ctx).Model(&models.Post{}).Where("user_id = ?", s.Author.ID)
+	case models.TargetComment:
+		query = f.DB.WithContext(ctx).Model(&models.Comment{}).Where("commenter_id = ?", s.Author.ID)
+	default:
+		return FilterResult{}, nil
+	}
+
+	var duplicates int64
+	if err := query.Where("id <> ? AND content = ? AND created_at > ?", s.TargetID, s.Content, time.Now().Add(-f.Window)).
+		Count(&duplicates).Error; err != nil {
+		return FilterResult{}, err
+	}
+	if duplicates == 0 {
+		return FilterResult{}, nil
+	}
+	return FilterResult{Score: f.Score, Reason: "duplicate of recent content"}, nil
+}
+
+// NewAccountFilter throttles accounts younger than MinAge to MaxPerHour new
+// posts and comments, and adds Score to everything they write.
+type NewAccountFilter struct {
+	DB         *gorm.DB
+	MinAge     time.Duration
+	MaxPerHour int64
+	Score      float64
+}
+
+func (f *NewAccountFilter) Check(ctx context.Context, s *Submission) (FilterResult, error) {
+	// accounts from before sign-up times were recorded are not new
+	if s.Author.CreatedAt.IsZero() || time.Since(s.Author.CreatedAt) >= f.MinAge {
+		return FilterResult{}, nil
+	}
+
+	if s.TargetID == 0 {
+		since := time.Now().Add(-time.Hour)
+		var posts, comments int64
+		if err := f.DB.WithContext(ctx).Model(&models.Post{}).Where("user_id = ? AND created_at > ?", s.Author.ID, since).
+			Count(&posts).Error; err != nil {
+			return FilterResult{}, err
+		}
+		if err := f.DB.WithContext(ctx).Model(&models.Comment{}).Where("commenter_id = ? AND created_at > ?", s.Author.ID, since).
+			Count(&comments).Error; err != nil {
+			return FilterResult{}, err
+		}
+		if posts+comments >= f.MaxPerHour {
+			return FilterResult{Reject: true, Reason: "new accounts are limited in how much they can post, try again later"}, nil
+		}
+	}
+
+	return FilterResult{Score: f.Score, Reason: "new account"}, nil
+}
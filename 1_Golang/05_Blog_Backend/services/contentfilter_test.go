@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Hello", "hello"},
+		{"ＦＵＬＬ width", "full width"},
+		{"Café naïve", "cafe naive"},
+		{"v1@gr4", "viagra"},
+		{"$5 off", "ss off"},
+		{"赌博", "赌博"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeText(tt.in); got != tt.want {
+			t.Errorf("NormalizeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBannedWordsFilter(t *testing.T) {
+	f := NewBannedWordsFilter([]string{"ass", "buy  Now!", "赌博", "коза", "łza", " ", ""})
+
+	tests := []struct {
+		text   string
+		reject bool
+	}{
+		{"what an ass", true},
+		{"A$$!", true},
+		{"first class", false},
+		{"assess", false},
+		{"please BUY, now", true},
+		{"buy\nnow", true},
+		{"buy it now", false},
+		{"buynow", false},
+		{"网上赌博网站", true},
+		{"коза", true},
+		{"козак", false},
+		{"łza", true},
+		{"słza", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		result, err := f.Check(context.Background(), &Submission{Content: tt.text})
+		if err != nil {
+			t.Fatalf("Check(%q): %v", tt.text, err)
+		}
+		if result.Reject != tt.reject {
+			t.Errorf("Check(%q).Reject = %v, want %v", tt.text, result.Reject, tt.reject)
+		}
+	}
+}
+
+func TestBannedWordsFilterChecksTitle(t *testing.T) {
+	f := NewBannedWordsFilter([]string{"spam"})
+	result, err := f.Check(context.Background(), &Submission{Title: "Spam", Content: "fine"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Reject {
+		t.Error("a banned word in the title is not rejected")
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"blog-backend/models"
+	"context"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobRecountCounters is the job type that reconciles the denormalized counters.
+const JobRecountCounters = "counters.recount"
+
+// CounterMismatch is a denormalized counter whose stored value differs from the
+// value computed from the source rows.
+type CounterMismatch struct {
+	Counter string `json:"counter"`
+	Key     string `json:"key"`
+	Stored  int64  `json:"stored"`
+	Actual  int64  `json:"actual"`
+}
+
+// counterColumn is a counter column of table together with a correlated
+// subquery on alias t computing its true value.
+type counterColumn struct {
+	Table  string
+	Column string
+	Actual string
+}
+
+var counterColumns = []counterColumn{
+	{"users", "post_count", "SELECT COUNT(*) FROM posts WHERE posts.user_id = t.id AND posts.deleted_at IS NULL"},
+	{"users", "follower_count", "SELECT COUNT(*) FROM follows WHERE follows.followee_id = t.id"},
+	{"users", "following_count", "SELECT COUNT(*) FROM follows WHERE follows.follower_id = t.id"},
+	{"posts", "comment_count", "SELECT COUNT(*) FROM comments WHERE comments.post_id = t.id AND comments.deleted_at IS NULL"},
+	{"posts", "view_count", "SELECT COALESCE(SUM(views), 0) FROM post_daily_views WHERE post_daily_views.post_id = t.id"},
+}
+
+// Recount compares every denormalized counter with its source rows and, when
+// fix is set, corrects the mismatches. A counter that changed since it was
+// checked is left alone and picked up by the next run.
+func Recount(ctx context.Context, db *gorm.DB, fix bool) ([]CounterMismatch, error) {
+	db = db.WithContext(ctx)
+	var mismatches []CounterMismatch
+
+	for _, counter := range counterColumns {
+		var rows []struct {
+			ID     uint
+			Stored int64
+			Actual int64
+		}
+		query := fmt.Sprintf("SELECT t.id, t.%[2]s AS stored, (%[3]s) AS actual FROM %[1]s t WHERE t.%[2]s <> (%[3]s)",
+			counter.Table, counter.Column, counter.Actual)
+		if err := db.Raw(query).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			mismatches = append(mismatches, CounterMismatch{
+				Counter: counter.Table + "." + counter.Column,
+				Key:     fmt.Sprint(row.ID),
+				Stored:  row.Stored,
+				Actual:  row.Actual,
+			})
+			if !fix {
+				continue
+			}
+			if err := db.Table(counter.Table).Where("id = ? AND "+counter.Column+" = ?", row.ID, row.Stored).
+				Update(counter.Column, row.Actual).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	reactions, err := recountReactions(db, fix)
+	if err != nil {
+		return nil, err
+	}
+	return append(mismatches, reactions...), nil
+}
+
+// recountReactions reconciles reaction_counts, whose rows are keyed by target
+// and kind instead of an id. Like the other counters, a count is only fixed
+// when it still has the value that was checked: the stored counts are read
+// before the reactions, so a reaction added in between changed the stored
+// count too, and the fix leaves it alone.
+func recountReactions(db *gorm.DB, fix bool) ([]CounterMismatch, error) {
+	type key struct {
+		TargetType string
+		TargetID   uint
+		Kind       string
+	}
+
+	var stored []models.ReactionCount
+	if err := db.Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	var actualRows []models.ReactionCount
+	if err := db.Model(&models.Reaction{}).Select("target_type, target_id, kind, COUNT(*) AS total").
+		Group("target_type, target_id, kind").Scan(&actualRows).Error; err != nil {
+		return nil, err
+	}
+	actual := make(map[key]int, len(actualRows))
+	for _, row := range actualRows {
+		actual[key{row.TargetType, row.TargetID, row.Kind}] = row.Total
+	}
+
+	var mismatches []CounterMismatch
+	check := func(k key, exists bool, storedTotal, actualTotal int) error {
+		if storedTotal == actualTotal {
+			return nil
+		}
+		mismatches = append(mismatches, CounterMismatch{
+			Counter: "reaction_counts.total",
+			Key:     fmt.Sprintf("%s:%d:%s", k.TargetType, k.TargetID, k.Kind),
+			Stored:  int64(storedTotal),
+			Actual:  int64(actualTotal),
+		})
+		if !fix {
+			return nil
+		}
+		if !exists {
+			// a row the hooks created in the meantime is theirs
+			return db.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.ReactionCount{TargetType: k.TargetType, TargetID: k.TargetID, Kind: k.Kind, Total: actualTotal}).Error
+		}
+		return db.Model(&models.ReactionCount{}).
+			Where("target_type = ? AND target_id = ? AND kind = ? AND total = ?", k.TargetType, k.TargetID, k.Kind, storedTotal).
+			Update("total", actualTotal).Error
+	}
+
+	for _, row := range stored {
+		k := key{row.TargetType, row.TargetID, row.Kind}
+		if err := check(k, true, row.Total, actual[k]); err != nil {
+			return nil, err
+		}
+		delete(actual, k)
+	}
+	// reactions without any stored count row
+	for k, total := range actual {
+		if err := check(k, false, 0, total); err != nil {
+			return nil, err
+		}
+	}
+	return mismatches, nil
+}
+
+// RecountJob returns the handler of JobRecountCounters, which fixes and logs drift.
+func RecountJob(db *gorm.DB) JobHandler {
+	return func(ctx context.Context, payload []byte) error {
+		mismatches, err := Recount(ctx, db, true)
+		if err != nil {
+			return err
+		}
+		for _, m := range mismatches {
+			log.Printf("Fixed counter %s[%s]: stored %d, actual %d", m.Counter, m.Key, m.Stored, m.Actual)
+		}
+		return nil
+	}
+}
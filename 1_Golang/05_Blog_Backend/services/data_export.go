@@ -0,0 +1,243 @@
+package services
+
+import (
+	"archive/zip"
+	"blog-backend/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostRecord is a post in a data export, self-contained so it can be imported
+// into another blog.
+type PostRecord struct {
+	ID            uint      `json:"id"`
+	Title         string    `json:"title"`
+	Author        string    `json:"author,omitempty"`
+	Slug          string    `json:"slug"`
+	Content       string    `json:"content"`
+	ContentFormat string    `json:"content_format"`
+	Tags          []string  `json:"tags"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Deleted       bool      `json:"deleted,omitempty"`
+}
+
+// CommentRecord is a comment in a data export.
+type CommentRecord struct {
+	ID        uint      `json:"id"`
+	PostID    uint      `json:"post_id"`
+	ParentID  *uint     `json:"parent_id,omitempty"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Deleted   bool      `json:"deleted,omitempty"`
+}
+
+// ProfileRecord is the account part of a data export.
+type ProfileRecord struct {
+	User                    models.User                    `json:"user"`
+	Email                   string                         `json:"email"`
+	Status                  string                         `json:"status"`
+	StatusReason            string                         `json:"status_reason,omitempty"`
+	StatusUntil             *time.Time                     `json:"status_until,omitempty"`
+	Identities              []models.Identity              `json:"identities"`
+	NotificationPreferences *models.NotificationPreference `json:"notification_preferences,omitempty"`
+	Following               []uint                         `json:"following"`
+	Followers               []uint                         `json:"followers"`
+}
+
+// WriteDataExport writes a ZIP archive of everything stored about the user:
+// the profile, posts, comments, reactions, bookmarks and the user's audit
+// trail as JSON, and the posts and comments as Markdown. Posts and comments
+// in the trash are included and marked deleted.
+func WriteDataExport(db *gorm.DB, userID uint, w io.Writer) error {
+	var profile ProfileRecord
+	if err := db.First(&profile.User, userID).Error; err != nil {
+		return err
+	}
+	profile.Email = profile.User.Email
+	profile.Status, profile.StatusReason, profile.StatusUntil = profile.User.Status, profile.User.StatusReason, profile.User.StatusUntil
+	if err := db.Where("user_id = ?", userID).Find(&profile.Identities).Error; err != nil {
+		return err
+	}
+	var preferences models.NotificationPreference
+	if err := db.Where("user_id = ?", userID).First(&preferences).Error; err == nil {
+		profile.NotificationPreferences = &preferences
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err := db.Model(&models.Follow{}).Where("follower_id = ?", userID).Order("id").
+		Pluck("followee_id", &profile.Following).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.Follow{}).Where("followee_id = ?", userID).Order("id").
+		Pluck("follower_id", &profile.Followers).Error; err != nil {
+		return err
+	}
+
+	var posts []models.Post
+	if err := db.Unscoped().Preload("Tags").Where("user_id = ?", userID).Order("id").Find(&posts).Error; err != nil {
+		return err
+	}
+	postRecords := make([]PostRecord, len(posts))
+	for i, post := range posts {
+		postRecords[i] = PostRecord{
+			ID:            post.ID,
+			Title:         post.Title,
+			Author:        profile.User.Username,
+			Slug:          post.Slug,
+			Content:       post.Content,
+			ContentFormat: post.ContentFormat,
+			Tags:          tagNames(post.Tags),
+			CreatedAt:     post.CreatedAt,
+			UpdatedAt:     post.UpdatedAt,
+			Deleted:       post.DeletedAt.Valid,
+		}
+	}
+
+	var comments []models.Comment
+	if err := db.Unscoped().Where("commenter_id = ?", userID).Order("id").Find(&comments).Error; err != nil {
+		return err
+	}
+	commentRecords := make([]CommentRecord, len(comments))
+	for i, comment := range comments {
+		commentRecords[i] = CommentRecord{
+			ID:        comment.ID,
+			PostID:    comment.PostId,
+			ParentID:  comment.ParentID,
+			Content:   comment.Content,
+			CreatedAt: comment.CreatedAt,
+			UpdatedAt: comment.UpdatedAt,
+			Deleted:   comment.DeletedAt.Valid,
+		}
+	}
+
+	var reactions []models.Reaction
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&reactions).Error; err != nil {
+		return err
+	}
+	var bookmarks []struct {
+		PostID    uint      `json:"post_id"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	if err := db.Model(&models.Bookmark{}).Where("user_id = ?", userID).Order("id").Find(&bookmarks).Error; err != nil {
+		return err
+	}
+	var audit []models.AuditEvent
+	if err := db.Where("actor_id = ?", userID).Order("id").Find(&audit).Error; err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", profile},
+		{"posts.json", postRecords},
+		{"comments.json", commentRecords},
+		{"reactions.json", reactions},
+		{"bookmarks.json", bookmarks},
+		{"audit.json", audit},
+	}
+	for _, file := range files {
+		body, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeZipFile(archive, file.name, body); err != nil {
+			return err
+		}
+	}
+
+	for _, post := range postRecords {
+		var body strings.Builder
+		WritePostMarkdown(&body, post)
+		if err := writeZipFile(archive, markdownPath(post), []byte(body.String())); err != nil {
+			return err
+		}
+	}
+
+	var body strings.Builder
+	body.WriteString("# Comments\n")
+	for _, comment := range commentRecords {
+		fmt.Fprintf(&body, "\n## On post %d, %s", comment.PostID, comment.CreatedAt.UTC().Format(time.RFC3339))
+		if comment.Deleted {
+			body.WriteString(" (deleted)")
+		}
+		fmt.Fprintf(&body, "\n\n%s\n", comment.Content)
+	}
+	if err := writeZipFile(archive, "comments.md", []byte(body.String())); err != nil {
+		return err
+	}
+
+	readme := fmt.Sprintf("# Data export of %s\n\nCreated %s.\n\n"+
+		"- profile.json: account, linked identities, notification preferences and follows\n"+
+		"- posts.json and posts/*.md: %d posts, Markdown with front matter\n"+
+		"- comments.json and comments.md: %d comments\n"+
+		"- reactions.json: %d reactions\n"+
+		"- bookmarks.json: %d bookmarks\n"+
+		"- audit.json: %d recorded actions, with IP address and user agent\n",
+		profile.User.Username, time.Now().UTC().Format(time.RFC3339),
+		len(postRecords), len(commentRecords), len(reactions), len(bookmarks), len(audit))
+	if err := writeZipFile(archive, "README.md", []byte(readme)); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// WritePostMarkdown writes the post as Markdown with a YAML front matter of
+// its title, author, slug, dates, tags and format.
+func WritePostMarkdown(w io.Writer, post PostRecord) {
+	tags, _ := json.Marshal(post.Tags)
+	if post.Tags == nil {
+		tags = []byte("[]")
+	}
+
+	fmt.Fprintf(w, "---\ntitle: %s\n", yamlString(post.Title))
+	if post.Author != "" {
+		fmt.Fprintf(w, "author: %s\n", yamlString(post.Author))
+	}
+	if post.Slug != "" {
+		fmt.Fprintf(w, "slug: %s\n", yamlString(post.Slug))
+	}
+	fmt.Fprintf(w, "date: %s\n", post.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "updated: %s\n", post.UpdatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "tags: %s\n", tags)
+	fmt.Fprintf(w, "format: %s\n", post.ContentFormat)
+	if post.Deleted {
+		io.WriteString(w, "deleted: true\n")
+	}
+	fmt.Fprintf(w, "---\n\n%s\n", post.Content)
+}
+
+// yamlString quotes s as a JSON string, which is a valid YAML scalar whatever s contains.
+func yamlString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+func markdownPath(post PostRecord) string {
+	name := strconv.FormatUint(uint64(post.ID), 10)
+	if post.Slug != "" {
+		// slugs may contain any letter but no path separators
+		name += "-" + post.Slug
+	}
+	return "posts/" + name + ".md"
+}
+
+func writeZipFile(archive *zip.Writer, name string, body []byte) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"blog-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EraseAccount deletes the user's posts, comments, reactions, follows and every
+// other row that belongs to the user, and pseudonymizes what stays for the
+// records of others: the user row keeps its ID for references such as revision
+// editors and report targets, and the user's audit events and reports lose
+// their IP addresses, user agents and free text. It returns the storage keys of
+// the deleted files. Every step can be run again, so a failed erasure is
+// finished by retrying it.
+func EraseAccount(db *gorm.DB, userID uint) ([]string, error) {
+	var keys []string
+
+	// posts go one at a time, as in the trash purge, to keep transactions small
+	for {
+		var postIDs []uint
+		if err := db.Unscoped().Model(&models.Post{}).Where("user_id = ?", userID).
+			Limit(purgeBatchSize).Pluck("id", &postIDs).Error; err != nil {
+			return keys, err
+		}
+		for _, postID := range postIDs {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				postKeys, err := purgePost(tx, postID)
+				keys = append(keys, postKeys...)
+				return err
+			})
+			if err != nil {
+				return keys, err
+			}
+		}
+		if len(postIDs) < purgeBatchSize {
+			break
+		}
+	}
+
+	for {
+		var comments []models.Comment
+		if err := db.Unscoped().Select("id", "post_id", "deleted_at").Where("commenter_id = ?", userID).
+			Limit(purgeBatchSize).Find(&comments).Error; err != nil {
+			return keys, err
+		}
+		if len(comments) == 0 {
+			break
+		}
+
+		ids := make([]uint, len(comments))
+		live := map[uint]int{}
+		for i, comment := range comments {
+			ids[i] = comment.ID
+			if !comment.DeletedAt.Valid {
+				live[comment.PostId]++
+			}
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// the bulk purge bypasses the hooks that keep comment_count
+			for postID, n := range live {
+				if err := tx.Model(&models.Post{}).Where("id = ?", postID).
+					UpdateColumn("comment_count", gorm.Expr("comment_count - ?", n)).Error; err != nil {
+					return err
+				}
+			}
+			return purgeComments(tx, ids)
+		})
+		if err != nil {
+			return keys, err
+		}
+	}
+
+	var attachments []models.Attachment
+	if err := db.Unscoped().Where("user_id = ?", userID).Find(&attachments).Error; err != nil {
+		return keys, err
+	}
+	for _, attachment := range attachments {
+		keys = append(keys, attachmentKeys(attachment)...)
+	}
+	var exportKeys []string
+	if err := db.Model(&models.DataExport{}).Where("user_id = ? AND storage_key <> ''", userID).
+		Pluck("storage_key", &exportKeys).Error; err != nil {
+		return keys, err
+	}
+	keys = append(keys, exportKeys...)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// one by one, so the hooks update the counters of the other side
+		var reactions []models.Reaction
+		if err := tx.Where("user_id = ?", userID).Find(&reactions).Error; err != nil {
+			return err
+		}
+		for i := range reactions {
+			if err := tx.Delete(&reactions[i]).Error; err != nil {
+				return err
+			}
+		}
+		var follows []models.Follow
+		if err := tx.Where("follower_id = ? OR followee_id = ?", userID, userID).Find(&follows).Error; err != nil {
+			return err
+		}
+		for i := range follows {
+			if err := tx.Delete(&follows[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Attachment{}).Error; err != nil {
+			return err
+		}
+		webhooks := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.Webhook{}).
+			Select("id").Where("user_id = ?", userID)
+		if err := tx.Where("webhook_id IN (?)", webhooks).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Webhook{}).Error; err != nil {
+			return err
+		}
+		// events about the user's content queued for, or sent to, webhooks of others
+		if err := tx.Where("subject_id = ?", userID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subject_id = ?", userID).Delete(&models.Job{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? OR actor_id = ?", userID, userID).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []any{&models.Bookmark{}, &models.Identity{}, &models.APIToken{}, &models.PasswordReset{},
+			&models.NotificationPreference{}, &models.DataExport{}} {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.AuditEvent{}).Where("actor_id = ?", userID).
+			Updates(map[string]any{"ip": "", "user_agent": "", "diff": ""}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Report{}).Where("reporter_id = ?", userID).
+			UpdateColumn("detail", "").Error; err != nil {
+			return err
+		}
+
+		// the unique username and email are freed for new accounts
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]any{
+			"username":                models.ErasedUsername(userID),
+			"email":                   models.ErasedEmail(userID),
+			"password":                "",
+			"role":                    models.RoleUser,
+			"status":                  models.UserErased,
+			"status_reason":           "",
+			"status_until":            nil,
+			"post_count":              0,
+			"follower_count":          0,
+			"following_count":         0,
+			"password_reset_required": false,
+			"sessions_valid_after":    time.Now(),
+		}).Error
+	})
+	return keys, err
+}
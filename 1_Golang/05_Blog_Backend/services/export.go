@@ -0,0 +1,278 @@
+package services
+
+import (
+	"blog-backend/models"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed templates/*.html
+var siteTemplates embed.FS
+
+// exportMarker is written into every export, only directories carrying it are
+// replaced by the next export.
+const exportMarker = ".blog-export"
+
+var siteFuncs = template.FuncMap{
+	"postPath":   func(post models.Post) string { return PostPermalink("", post) },
+	"authorPath": AuthorPath,
+	"tagPath":    TagPath,
+	"date":       func(t time.Time) string { return t.Format("2006-01-02") },
+	"iso":        func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	// post and comment HTML is sanitized when it is saved
+	"safe": func(s string) template.HTML { return template.HTML(s) },
+}
+
+// LoadSiteTemplates parses list.html, post.html and their partials from dir,
+// or the built-in templates when dir is empty.
+func LoadSiteTemplates(dir string) (*template.Template, error) {
+	t := template.New("site").Funcs(siteFuncs)
+	if dir == "" {
+		return t.ParseFS(siteTemplates, "templates/*.html")
+	}
+	return t.ParseGlob(filepath.Join(dir, "*.html"))
+}
+
+// SitePage is the data the static site templates are executed with.
+type SitePage struct {
+	SiteTitle string
+	Title     string
+	Canonical string
+	Posts     []models.Post
+	Post      *models.Post
+	PrevPage  string
+	NextPage  string
+}
+
+// StaticExport renders the published blog to a directory of HTML files that
+// can be served from a CDN. A page at /posts/hello is written to
+// posts/hello/index.html, so the host must serve index.html for directories.
+type StaticExport struct {
+	Posts     *PostReader
+	SiteURL   string
+	SiteTitle string
+	PageSize  int
+	Templates *template.Template
+}
+
+// ExportResult counts what an export wrote.
+type ExportResult struct {
+	Posts   int
+	Authors int
+	Tags    int
+	Files   int
+}
+
+// Export renders the site into a new directory and then swaps it in for dir,
+// so a failed export leaves the previous one untouched and removed posts do
+// not linger. An existing dir is only replaced when it is empty or an earlier export.
+func (e *StaticExport) Export(dir string) (*ExportResult, error) {
+	if err := checkExportDir(dir); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.MkdirTemp(filepath.Dir(filepath.Clean(dir)), ".export-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	result, err := e.render(tmp)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, exportMarker), nil, 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(tmp, 0o755); err != nil {
+		return nil, err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	return result, os.Rename(tmp, dir)
+}
+
+func checkExportDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(entries) == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, exportMarker)); err != nil {
+		return fmt.Errorf("refusing to replace %s: it is not empty and was not written by export", dir)
+	}
+	return nil
+}
+
+func (e *StaticExport) render(root string) (*ExportResult, error) {
+	posts, err := e.Posts.List(Newest(0))
+	if err != nil {
+		return nil, err
+	}
+	result := &ExportResult{Posts: len(posts)}
+
+	for _, post := range posts {
+		full, err := e.Posts.Get(post.ID)
+		if err != nil {
+			return nil, fmt.Errorf("post %d: %w", post.ID, err)
+		}
+		path := PostPermalink("", post)
+		if err := e.writePage(root, path, "post.html", &SitePage{Title: post.Title, Post: full}); err != nil {
+			return nil, err
+		}
+		result.Files++
+	}
+
+	files, err := e.writeList(root, "", "", posts)
+	if err != nil {
+		return nil, err
+	}
+	result.Files += files
+
+	// group by author and tag, keeping the newest-first order
+	var authors []uint
+	byAuthor := map[uint][]models.Post{}
+	var tags []string
+	byTag := map[string][]models.Post{}
+	for _, post := range posts {
+		if _, ok := byAuthor[post.UserID]; !ok {
+			authors = append(authors, post.UserID)
+		}
+		byAuthor[post.UserID] = append(byAuthor[post.UserID], post)
+		for _, tag := range post.Tags {
+			if _, ok := byTag[tag.Name]; !ok {
+				tags = append(tags, tag.Name)
+			}
+			byTag[tag.Name] = append(byTag[tag.Name], post)
+		}
+	}
+
+	for _, id := range authors {
+		title := "Posts by " + byAuthor[id][0].User.Username
+		files, err := e.writeList(root, AuthorPath(id), title, byAuthor[id])
+		if err != nil {
+			return nil, err
+		}
+		result.Files += files
+	}
+	for _, name := range tags {
+		files, err := e.writeList(root, TagPath(name), "Posts tagged #"+name, byTag[name])
+		if err != nil {
+			return nil, err
+		}
+		result.Files += files
+	}
+	result.Authors, result.Tags = len(authors), len(tags)
+
+	files, err = e.writeFeeds(root, posts)
+	if err != nil {
+		return nil, err
+	}
+	result.Files += files
+	return result, nil
+}
+
+// writeList writes the posts as pages of PageSize at base, base/page/2, ...
+func (e *StaticExport) writeList(root, base, title string, posts []models.Post) (int, error) {
+	size := e.PageSize
+	if size <= 0 {
+		size = 20
+	}
+	pagePath := func(n int) string {
+		if n == 1 {
+			return base + "/"
+		}
+		return base + "/page/" + strconv.Itoa(n)
+	}
+
+	pages := max(1, (len(posts)+size-1)/size)
+	for n := 1; n <= pages; n++ {
+		page := &SitePage{Title: title, Posts: posts[(n-1)*size : min(n*size, len(posts))]}
+		if n > 1 {
+			page.PrevPage = pagePath(n - 1)
+			page.Title = fmt.Sprintf("%s (page %d)", title, n)
+			if title == "" {
+				page.Title = fmt.Sprintf("Page %d", n)
+			}
+		}
+		if n < pages {
+			page.NextPage = pagePath(n + 1)
+		}
+		if err := e.writePage(root, pagePath(n), "list.html", page); err != nil {
+			return 0, err
+		}
+	}
+	return pages, nil
+}
+
+// writeFeeds adds the site-wide feeds and the sitemap, as served by the API.
+func (e *StaticExport) writeFeeds(root string, posts []models.Post) (int, error) {
+	feed := SiteFeed(e.SiteTitle, e.SiteURL)
+	feed.Posts = posts[:min(len(posts), FeedSize)]
+	for _, post := range feed.Posts {
+		feed.Updated = latest(feed.Updated, post.UpdatedAt)
+	}
+
+	files := 0
+	for format := range FeedContentTypes {
+		feed.FeedURL = e.SiteURL + "/feed." + format
+		var body bytes.Buffer
+		if err := RenderFeed(&body, format, feed, e.SiteURL); err != nil {
+			return 0, err
+		}
+		if err := os.WriteFile(filepath.Join(root, "feed."+format), body.Bytes(), 0o644); err != nil {
+			return 0, err
+		}
+		files++
+	}
+
+	sitemap, err := BuildSitemap(e.Posts, e.SiteURL)
+	if err != nil {
+		return 0, err
+	}
+	var body bytes.Buffer
+	if err := sitemap.Write(&body); err != nil {
+		return 0, err
+	}
+	return files + 1, os.WriteFile(filepath.Join(root, "sitemap.xml"), body.Bytes(), 0o644)
+}
+
+// writePage executes the template into <sitePath>/index.html under root.
+func (e *StaticExport) writePage(root, sitePath, name string, page *SitePage) error {
+	rel, err := url.PathUnescape(sitePath)
+	if err != nil {
+		return err
+	}
+	for _, segment := range strings.Split(strings.Trim(rel, "/"), "/") {
+		if segment == "." || segment == ".." || strings.ContainsRune(segment, '\\') {
+			return fmt.Errorf("invalid page path %q", sitePath)
+		}
+	}
+
+	page.SiteTitle = e.SiteTitle
+	page.Canonical = e.SiteURL + sitePath
+
+	var body bytes.Buffer
+	if err := e.Templates.ExecuteTemplate(&body, name, page); err != nil {
+		return fmt.Errorf("%s: %w", sitePath, err)
+	}
+
+	dir := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "index.html"), body.Bytes(), 0o644)
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"blog-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// FeedSource builds a user's home feed. Posts are returned newest first,
+// beforeID is the cursor (0 for the first page).
+type FeedSource interface {
+	Feed(userID uint, beforeID uint, limit int) ([]models.Post, error)
+}
+
+// FanOutOnReadFeed queries the posts of followed authors at read time. A
+// precomputed timeline table can replace it behind FeedSource once reads
+// become too expensive.
+type FanOutOnReadFeed struct {
+	DB *gorm.DB
+}
+
+func (f *FanOutOnReadFeed) Feed(userID uint, beforeID uint, limit int) ([]models.Post, error) {
+	query := f.DB.Scopes(models.VisiblePosts).Joins("User").
+		Where("posts.user_id IN (?)", f.DB.Model(&models.Follow{}).Select("followee_id").Where("follower_id = ?", userID))
+	if beforeID > 0 {
+		query = query.Where("posts.id < ?", beforeID)
+	}
+
+	var posts []models.Post
+	err := query.Order("posts.id desc").Limit(limit).Find(&posts).Error
+	return posts, err
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Event types published on the hub.
+const (
+	EventPostUpdated    = "post.updated"
+	EventPostDeleted    = "post.deleted"
+	EventCommentCreated = "comment.created"
+	EventCommentUpdated = "comment.updated"
+	EventCommentDeleted = "comment.deleted"
+	EventNotification   = "notification"
+)
+
+// subscriptionBuffer is the number of events a subscriber may fall behind
+// before it is considered slow and disconnected.
+const subscriptionBuffer = 32
+
+// Event is a message delivered to subscribers of a topic.
+type Event struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// PostTopic is the topic of events about a post and its comments.
+func PostTopic(postID uint) string {
+	return fmt.Sprintf("post:%d", postID)
+}
+
+// UserTopic is the topic of events addressed to a single user.
+func UserTopic(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// Subscription receives the events of one topic on C. C is closed when the
+// subscription is cancelled or the subscriber was too slow to keep up.
+type Subscription struct {
+	C     <-chan Event
+	c     chan Event
+	topic string
+	hub   *Hub
+	once  sync.Once
+}
+
+// Cancel unsubscribes and closes C, it is safe to call more than once.
+func (s *Subscription) Cancel() {
+	s.hub.remove(s)
+}
+
+// Hub is an in-process publish/subscribe broker for real-time updates.
+type Hub struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{topics: make(map[string]map[*Subscription]struct{})}
+}
+
+// Subscribe starts receiving the events published on the topic.
+func (h *Hub) Subscribe(topic string) *Subscription {
+	ch := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{C: ch, c: ch, topic: topic, hub: h}
+
+	h.mu.Lock()
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Subscription]struct{})
+	}
+	h.topics[topic][sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+// Publish delivers the event to every subscriber of the topic without blocking.
+// Subscribers whose buffer is full are dropped, their clients reconnect and
+// refetch instead of holding back everyone else. A nil hub ignores events.
+func (h *Hub) Publish(topic string, event Event) {
+	if h == nil {
+		return
+	}
+
+	var slow []*Subscription
+
+	h.mu.RLock()
+	for sub := range h.topics[topic] {
+		select {
+		case sub.c <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		log.Printf("Dropping slow subscriber of %s", topic)
+		h.remove(sub)
+	}
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	sub.once.Do(func() {
+		h.mu.Lock()
+		delete(h.topics[sub.topic], sub)
+		if len(h.topics[sub.topic]) == 0 {
+			delete(h.topics, sub.topic)
+		}
+		close(sub.c)
+		h.mu.Unlock()
+	})
+}
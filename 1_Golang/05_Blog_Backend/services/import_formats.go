@@ -0,0 +1,345 @@
+package services
+
+import (
+	"archive/zip"
+	"blog-backend/utils"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// maxImportArchiveEntry caps how much of a file in a ZIP archive is read.
+const maxImportArchiveEntry = 64 << 20
+
+// importTimeLayouts are the date formats accepted in front matter, the ones
+// without a zone being read as UTC.
+var importTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseImportFile reads the posts of a file, telling its format by its
+// extension: .md and .markdown for a Markdown post with a YAML front matter,
+// .xml for a WordPress export (WXR), .json for the posts.json of a data export
+// and .zip for a whole data export.
+func ParseImportFile(name string, data []byte) ([]ImportItem, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		item, err := parseMarkdownPost(name, data)
+		if err != nil {
+			return nil, err
+		}
+		return []ImportItem{item}, nil
+	case ".xml":
+		return parseWXR(name, data)
+	case ".json":
+		return parsePostRecords(name, data, "")
+	case ".zip":
+		return parseDataExport(name, data)
+	default:
+		return nil, errors.New("unsupported file type, expected .md, .markdown, .xml, .json or .zip")
+	}
+}
+
+// parseMarkdownPost reads a post in the format of Jekyll, Hugo and our own
+// exports: a front matter between --- lines, then the content.
+func parseMarkdownPost(name string, data []byte) (ImportItem, error) {
+	text := strings.TrimPrefix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\ufeff")
+	lines := strings.Split(text, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return ImportItem{}, errors.New("front matter is missing")
+	}
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return ImportItem{}, errors.New("front matter is not closed")
+	}
+
+	var meta map[string]any
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[1:end], "\n")), &meta); err != nil {
+		return ImportItem{}, fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	item := ImportItem{
+		Source:        name,
+		Title:         metaString(meta["title"]),
+		Slug:          metaString(meta["slug"]),
+		Content:       strings.TrimSpace(strings.Join(lines[end+1:], "\n")),
+		ContentFormat: utils.FormatMarkdown,
+		Tags:          append(metaList(meta["tags"]), metaList(meta["categories"])...),
+	}
+	if format := metaString(meta["format"]); format != "" {
+		item.ContentFormat = format
+	}
+	item.Author = metaString(meta["author"])
+	if authors := metaList(meta["authors"]); item.Author == "" && len(authors) > 0 {
+		item.Author = authors[0]
+	}
+
+	var err error
+	if item.CreatedAt, err = parseImportTime(meta["date"]); err != nil {
+		return ImportItem{}, err
+	}
+	if item.CreatedAt.IsZero() {
+		// Jekyll names posts 2006-01-02-title.md
+		if base := path.Base(name); len(base) > 10 {
+			item.CreatedAt, _ = time.Parse("2006-01-02", base[:10])
+		}
+	}
+	for _, key := range []string{"updated", "lastmod", "last_modified_at"} {
+		if item.UpdatedAt, err = parseImportTime(meta[key]); err != nil {
+			return ImportItem{}, err
+		}
+		if !item.UpdatedAt.IsZero() {
+			break
+		}
+	}
+
+	switch {
+	case meta["draft"] == true, meta["published"] == false:
+		item.Skip = "draft"
+	case meta["deleted"] == true:
+		item.Skip = "deleted in the source"
+	}
+	return item, nil
+}
+
+// metaString returns a front matter scalar as a string.
+func metaString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// metaList returns a front matter list, or a comma separated string, as strings.
+func metaList(value any) []string {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		list := make([]string, 0, len(value))
+		for _, v := range value {
+			list = append(list, metaString(v))
+		}
+		return list
+	default:
+		return strings.Split(metaString(value), ",")
+	}
+}
+
+func parseImportTime(value any) (time.Time, error) {
+	switch value := value.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return value, nil
+	case string:
+		for _, layout := range importTimeLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %v", value)
+}
+
+// wxr is the part of a WordPress export that is imported. Elements of the wp
+// namespace are matched by their local names, as the namespace changes with
+// the version of the export format.
+type wxr struct {
+	Channel struct {
+		Authors []struct {
+			Login string `xml:"author_login"`
+			Email string `xml:"author_email"`
+		} `xml:"author"`
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrItem struct {
+	Title           string `xml:"title"`
+	PubDate         string `xml:"pubDate"`
+	Creator         string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Content         string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PostID          uint   `xml:"post_id"`
+	PostDate        string `xml:"post_date"`
+	PostDateGMT     string `xml:"post_date_gmt"`
+	PostModified    string `xml:"post_modified"`
+	PostModifiedGMT string `xml:"post_modified_gmt"`
+	PostName        string `xml:"post_name"`
+	Status          string `xml:"status"`
+	PostType        string `xml:"post_type"`
+	PostPassword    string `xml:"post_password"`
+	Categories      []struct {
+		Domain   string `xml:"domain,attr"`
+		Nicename string `xml:"nicename,attr"`
+		Name     string `xml:",chardata"`
+	} `xml:"category"`
+}
+
+// parseWXR reads the posts of a WordPress export. Pages, attachments and other
+// types of items are left out; posts that are not published are skipped.
+func parseWXR(name string, data []byte) ([]ImportItem, error) {
+	var doc wxr
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid WordPress export: %w", err)
+	}
+
+	emails := map[string]string{}
+	for _, author := range doc.Channel.Authors {
+		emails[author.Login] = author.Email
+	}
+
+	var items []ImportItem
+	for _, post := range doc.Channel.Items {
+		if post.PostType != "post" {
+			continue
+		}
+		item := ImportItem{
+			Source:        fmt.Sprintf("%s#%d", name, post.PostID),
+			Title:         post.Title,
+			Slug:          post.PostName,
+			Content:       strings.TrimSpace(post.Content),
+			ContentFormat: utils.FormatHTML,
+			Author:        post.Creator,
+			AuthorEmail:   emails[post.Creator],
+			CreatedAt:     wxrTime(post.PostDateGMT, post.PostDate),
+			UpdatedAt:     wxrTime(post.PostModifiedGMT, post.PostModified),
+		}
+		if item.CreatedAt.IsZero() {
+			item.CreatedAt, _ = time.Parse(time.RFC1123Z, post.PubDate)
+		}
+		for _, category := range post.Categories {
+			if (category.Domain == "post_tag" || category.Domain == "category") && category.Nicename != "uncategorized" {
+				item.Tags = append(item.Tags, category.Name)
+			}
+		}
+
+		switch {
+		case post.Status != "publish":
+			item.Skip = "status " + post.Status
+		case post.PostPassword != "":
+			item.Skip = "password protected"
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// wxrTime parses the first of the dates that is set, WordPress writes
+// 0000-00-00 00:00:00 for dates it does not know.
+func wxrTime(values ...string) time.Time {
+	for _, value := range values {
+		if t, err := time.Parse("2006-01-02 15:04:05", value); err == nil && t.Year() > 1 {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parsePostRecords reads the posts.json of a data export. Records without an
+// author, from exports made before they had one, get the given author.
+func parsePostRecords(name string, data []byte, author string) ([]ImportItem, error) {
+	var records []PostRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("invalid posts.json: %w", err)
+	}
+
+	items := make([]ImportItem, len(records))
+	for i, record := range records {
+		items[i] = ImportItem{
+			Source:        fmt.Sprintf("%s#%d", name, record.ID),
+			Title:         record.Title,
+			Slug:          record.Slug,
+			Content:       record.Content,
+			ContentFormat: record.ContentFormat,
+			Tags:          record.Tags,
+			Author:        record.Author,
+			CreatedAt:     record.CreatedAt,
+			UpdatedAt:     record.UpdatedAt,
+		}
+		if items[i].Author == "" {
+			items[i].Author = author
+		}
+		if record.Deleted {
+			items[i].Skip = "deleted in the source"
+		}
+	}
+	return items, nil
+}
+
+// parseDataExport reads the posts.json of a data export archive, with the
+// exporting user as the author.
+func parseDataExport(name string, data []byte) ([]ImportItem, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+
+	var posts, profile []byte
+	for _, file := range archive.File {
+		if file.Name != "posts.json" && file.Name != "profile.json" {
+			continue
+		}
+		body, err := readArchiveFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if file.Name == "posts.json" {
+			posts = body
+		} else {
+			profile = body
+		}
+	}
+	if posts == nil {
+		return nil, errors.New("posts.json is missing, not a data export")
+	}
+
+	var record ProfileRecord
+	if profile != nil {
+		if err := json.Unmarshal(profile, &record); err != nil {
+			return nil, fmt.Errorf("invalid profile.json: %w", err)
+		}
+	}
+	return parsePostRecords(name, posts, record.User.Username)
+}
+
+func readArchiveFile(file *zip.File) ([]byte, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.Name, err)
+	}
+	defer r.Close()
+
+	body, err := io.ReadAll(io.LimitReader(r, maxImportArchiveEntry+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.Name, err)
+	}
+	if len(body) > maxImportArchiveEntry {
+		return nil, fmt.Errorf("%s exceeds the %d byte limit", file.Name, maxImportArchiveEntry)
+	}
+	return body, nil
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func mustTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseMarkdownPost(t *testing.T) {
+	tests := []struct {
+		name, file, data string
+		want             ImportItem
+	}{
+		{
+			name: "front matter",
+			file: "hello.md",
+			data: "---\ntitle: Hello\nslug: hello-world\ndate: 2020-03-04 05:06:07 +0200\nupdated: 2021-01-02\ntags: [go, web]\ncategories: notes\nauthor: alice\n---\n\n# Hello\n",
+			want: ImportItem{
+				Source: "hello.md", Title: "Hello", Slug: "hello-world", Content: "# Hello", ContentFormat: "markdown",
+				Tags: []string{"go", "web", "notes"}, Author: "alice",
+				CreatedAt: mustTime("2020-03-04T03:06:07Z"), UpdatedAt: mustTime("2021-01-02T00:00:00Z"),
+			},
+		},
+		{
+			name: "jekyll date from the file name",
+			file: "_posts/2019-12-31-new-year.markdown",
+			data: "\ufeff---\r\ntitle: New year\r\nauthors: [bob, carol]\r\n---\r\nbody\r\n",
+			want: ImportItem{
+				Source: "_posts/2019-12-31-new-year.markdown", Title: "New year", Content: "body", ContentFormat: "markdown",
+				Tags: []string{}, Author: "bob", CreatedAt: mustTime("2019-12-31T00:00:00Z"),
+			},
+		},
+		{
+			name: "draft",
+			file: "draft.md",
+			data: "---\ntitle: Later\ndraft: true\nformat: html\n---\n<p>x</p>",
+			want: ImportItem{
+				Source: "draft.md", Title: "Later", Content: "<p>x</p>", ContentFormat: "html", Tags: []string{}, Skip: "draft",
+			},
+		},
+		{
+			name: "unpublished",
+			file: "old.md",
+			data: "---\ntitle: Old\npublished: false\n---\n",
+			want: ImportItem{Source: "old.md", Title: "Old", ContentFormat: "markdown", Tags: []string{}, Skip: "draft"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := ParseImportFile(tt.file, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("got %d items, want 1", len(items))
+			}
+			got := items[0]
+			if !got.CreatedAt.Equal(tt.want.CreatedAt) || !got.UpdatedAt.Equal(tt.want.UpdatedAt) {
+				t.Errorf("dates = %v, %v, want %v, %v", got.CreatedAt, got.UpdatedAt, tt.want.CreatedAt, tt.want.UpdatedAt)
+			}
+			got.CreatedAt, got.UpdatedAt = tt.want.CreatedAt, tt.want.UpdatedAt
+			if len(got.Tags) == 0 {
+				got.Tags = []string{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownPostErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"no front matter": "# Title\n",
+		"not closed":      "---\ntitle: x\n",
+		"invalid yaml":    "---\ntitle: [x\n---\n",
+		"invalid date":    "---\ndate: yesterday\n---\n",
+	} {
+		if _, err := ParseImportFile("post.md", []byte(data)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+const testWXR = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<wp:author><wp:author_login>alice</wp:author_login><wp:author_email>alice@example.com</wp:author_email></wp:author>
+	<item>
+		<title>Published</title>
+		<dc:creator>alice</dc:creator>
+		<content:encoded><![CDATA[ <p>Hi</p> ]]></content:encoded>
+		<wp:post_id>7</wp:post_id>
+		<wp:post_date>2020-01-02 10:00:00</wp:post_date>
+		<wp:post_date_gmt>2020-01-02 08:00:00</wp:post_date_gmt>
+		<wp:post_modified_gmt>0000-00-00 00:00:00</wp:post_modified_gmt>
+		<wp:post_name>published</wp:post_name>
+		<wp:status>publish</wp:status>
+		<wp:post_type>post</wp:post_type>
+		<category domain="category" nicename="uncategorized"><![CDATA[Uncategorized]]></category>
+		<category domain="post_tag" nicename="go"><![CDATA[Go]]></category>
+		<category domain="post_format" nicename="post-format-aside"><![CDATA[Aside]]></category>
+	</item>
+	<item>
+		<title>Draft</title>
+		<dc:creator>bob</dc:creator>
+		<pubDate>Mon, 06 Jan 2020 12:00:00 +0000</pubDate>
+		<wp:post_id>8</wp:post_id>
+		<wp:status>draft</wp:status>
+		<wp:post_type>post</wp:post_type>
+	</item>
+	<item>
+		<title>Secret</title>
+		<wp:post_id>9</wp:post_id>
+		<wp:status>publish</wp:status>
+		<wp:post_type>post</wp:post_type>
+		<wp:post_password>pw</wp:post_password>
+	</item>
+	<item>
+		<title>About</title>
+		<wp:post_id>10</wp:post_id>
+		<wp:status>publish</wp:status>
+		<wp:post_type>page</wp:post_type>
+	</item>
+</channel>
+</rss>`
+
+func TestParseWXR(t *testing.T) {
+	items, err := ParseImportFile("blog.XML", []byte(testWXR))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("got %d items, want the 3 posts", len(items))
+	}
+
+	published := items[0]
+	want := ImportItem{
+		Source: "blog.XML#7", Title: "Published", Slug: "published", Content: "<p>Hi</p>", ContentFormat: "html",
+		Tags: []string{"Go"}, Author: "alice", AuthorEmail: "alice@example.com", CreatedAt: mustTime("2020-01-02T08:00:00Z"),
+	}
+	if !reflect.DeepEqual(published, want) {
+		t.Errorf("got %+v, want %+v", published, want)
+	}
+
+	if items[1].Skip != "status draft" || items[1].AuthorEmail != "" || !items[1].CreatedAt.Equal(mustTime("2020-01-06T12:00:00Z")) {
+		t.Errorf("draft = %+v", items[1])
+	}
+	if items[2].Skip != "password protected" {
+		t.Errorf("password protected post skip = %q", items[2].Skip)
+	}
+}
+
+const testPostsJSON = `[
+	{"id": 1, "title": "One", "slug": "one", "content": "c", "content_format": "markdown", "tags": ["a"], "created_at": "2020-01-02T03:04:05Z", "updated_at": "2020-02-02T03:04:05Z"},
+	{"id": 2, "title": "Two", "author": "bob", "content": "d", "created_at": "2020-01-03T00:00:00Z", "deleted": true}
+]`
+
+func TestParsePostRecords(t *testing.T) {
+	items, err := ParseImportFile("posts.json", []byte(testPostsJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ImportItem{
+		{Source: "posts.json#1", Title: "One", Slug: "one", Content: "c", ContentFormat: "markdown", Tags: []string{"a"},
+			CreatedAt: mustTime("2020-01-02T03:04:05Z"), UpdatedAt: mustTime("2020-02-02T03:04:05Z")},
+		{Source: "posts.json#2", Title: "Two", Content: "d", Author: "bob",
+			CreatedAt: mustTime("2020-01-03T00:00:00Z"), Skip: "deleted in the source"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("got %+v, want %+v", items, want)
+	}
+
+	if _, err := ParseImportFile("posts.json", []byte(`{"id": 1}`)); err == nil {
+		t.Error("an object instead of a list is accepted")
+	}
+}
+
+func TestParseDataExport(t *testing.T) {
+	archive := func(files map[string]string) []byte {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for name, body := range files {
+			f, err := w.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write([]byte(body))
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	items, err := ParseImportFile("export.zip", archive(map[string]string{
+		"posts.json":   testPostsJSON,
+		"profile.json": `{"user": {"id": 3, "username": "carol"}}`,
+		"readme.txt":   "ignored",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Author != "carol" || items[1].Author != "bob" {
+		t.Errorf("authors are not taken from the profile: %+v", items)
+	}
+
+	if _, err := ParseImportFile("export.zip", archive(map[string]string{"profile.json": "{}"})); err == nil {
+		t.Error("an archive without posts.json is accepted")
+	}
+	if _, err := ParseImportFile("export.zip", []byte("not a zip")); err == nil {
+		t.Error("an invalid archive is accepted")
+	}
+}
+
+func TestParseImportFileUnsupported(t *testing.T) {
+	if _, err := ParseImportFile("post.txt", []byte("x")); err == nil {
+		t.Error("a .txt file is accepted")
+	}
+}
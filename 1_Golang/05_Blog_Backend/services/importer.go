@@ -0,0 +1,341 @@
+package services
+
+import (
+	"blog-backend/models"
+	"blog-backend/utils"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Import statuses of an item.
+const (
+	ImportCreated = "created"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+// ImportItem is a post read from an import file.
+type ImportItem struct {
+	// Source names the file and, for files of several posts, the post in it.
+	Source        string
+	Title         string
+	Slug          string
+	Content       string
+	ContentFormat string
+	Tags          []string
+	Author        string
+	AuthorEmail   string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	// Skip is why the item is not imported, e.g. because it is a draft.
+	Skip string
+}
+
+// ImportFile is a file to import, named like on disk as its extension tells its format.
+type ImportFile struct {
+	Name string
+	Data []byte
+}
+
+// ImportResult is what became of an item. In a dry run, created items are
+// rolled back and have no PostID.
+type ImportResult struct {
+	Source string `json:"source"`
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status"`
+	PostID uint   `json:"post_id,omitempty"`
+	Slug   string `json:"slug,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport is the result of every item of an import, with the totals.
+type ImportReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Created int            `json:"created"`
+	Skipped int            `json:"skipped"`
+	Failed  int            `json:"failed"`
+	Items   []ImportResult `json:"items"`
+}
+
+func (r *ImportReport) add(result ImportResult) {
+	switch result.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportSkipped:
+		r.Skipped++
+	default:
+		r.Failed++
+	}
+	r.Items = append(r.Items, result)
+}
+
+// Limits of imported posts, the same as for posts created through the API.
+const (
+	maxImportTitle   = 200
+	maxImportTags    = 10
+	maxImportTagName = 64
+)
+
+// ErrUnknownDefaultAuthor is returned by Import when the default author does not exist.
+var ErrUnknownDefaultAuthor = errors.New("unknown default author")
+
+var (
+	errImportDuplicate = errors.New("already imported")
+	errImportDryRun    = errors.New("dry run")
+)
+
+// Importer creates posts from the files of another blog, or of an export of
+// this one, keeping their dates. Every item is imported in a transaction of
+// its own, so one that fails does not stop the others. Imported posts skip the
+// content filter and trigger no webhooks, as they were published long ago.
+type Importer struct {
+	DB                *gorm.DB
+	AuthorScopedSlugs bool
+	// Authors maps author names of the source to usernames.
+	Authors map[string]string
+	// DefaultAuthor is the username of the author of posts whose own author
+	// is not found; without it, such posts fail.
+	DefaultAuthor string
+	// DryRun rolls every item back after importing it.
+	DryRun bool
+
+	users map[string]*models.User
+}
+
+// Import imports the posts of the files. Posts already imported, i.e. of the
+// same author and title created at the same time, are skipped, so an import
+// can be run again after fixing the items that failed.
+func (im *Importer) Import(files []ImportFile) (*ImportReport, error) {
+	im.users = map[string]*models.User{}
+	if im.DefaultAuthor != "" {
+		if _, err := im.findUser("username = ?", im.DefaultAuthor); errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownDefaultAuthor, im.DefaultAuthor)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &ImportReport{DryRun: im.DryRun, Items: []ImportResult{}}
+	for _, file := range files {
+		items, err := ParseImportFile(file.Name, file.Data)
+		if err != nil {
+			report.add(ImportResult{Source: file.Name, Status: ImportFailed, Error: err.Error()})
+			continue
+		}
+		for _, item := range items {
+			report.add(im.importItem(item))
+		}
+	}
+	return report, nil
+}
+
+func (im *Importer) importItem(item ImportItem) ImportResult {
+	result := ImportResult{Source: item.Source, Title: item.Title, Status: ImportFailed}
+	if item.Skip != "" {
+		result.Status = ImportSkipped
+		result.Reason = item.Skip
+		return result
+	}
+	if err := normalizeImportItem(&item); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Title = item.Title
+
+	author, err := im.author(item)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	post := models.Post{
+		UserID:        author.ID,
+		Title:         item.Title,
+		Content:       item.Content,
+		ContentFormat: item.ContentFormat,
+		CreatedAt:     item.CreatedAt,
+		UpdatedAt:     item.UpdatedAt,
+	}
+	err = im.DB.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Unscoped().Model(&models.Post{}).
+			Where("user_id = ? AND title = ? AND created_at = ?", post.UserID, post.Title, post.CreatedAt).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errImportDuplicate
+		}
+
+		if err := tx.Create(&post).Error; err != nil {
+			return err
+		}
+		revision, err := models.CreateRevision(tx, &post, post.UserID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(revision).UpdateColumn("created_at", post.UpdatedAt).Error; err != nil {
+			return err
+		}
+		if err := im.assignSlug(tx, &post, item.Slug); err != nil {
+			return err
+		}
+		if err := models.SetPostTags(tx, &post, item.Tags); err != nil {
+			return err
+		}
+		// saving the tags touched the post
+		if err := tx.Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumn("updated_at", item.UpdatedAt).Error; err != nil {
+			return err
+		}
+		if im.DryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errImportDuplicate):
+		result.Status = ImportSkipped
+		result.Reason = err.Error()
+	case errors.Is(err, errImportDryRun):
+		result.Status = ImportCreated
+		result.Slug = post.Slug
+	case err != nil:
+		result.Error = err.Error()
+	default:
+		result.Status = ImportCreated
+		result.PostID = post.ID
+		result.Slug = post.Slug
+	}
+	return result
+}
+
+// normalizeImportItem checks the item against the limits of posts created
+// through the API and fills in what it may leave out.
+func normalizeImportItem(item *ImportItem) error {
+	item.Title = strings.TrimSpace(item.Title)
+	switch {
+	case item.Title == "":
+		return errors.New("title is missing")
+	case utf8.RuneCountInString(item.Title) > maxImportTitle:
+		return fmt.Errorf("title is longer than %d characters", maxImportTitle)
+	case strings.TrimSpace(item.Content) == "":
+		return errors.New("content is missing")
+	case item.CreatedAt.IsZero():
+		return errors.New("date is missing")
+	}
+
+	switch item.ContentFormat {
+	case "":
+		item.ContentFormat = utils.FormatPlain
+	case utils.FormatPlain, utils.FormatMarkdown, utils.FormatHTML:
+	default:
+		return fmt.Errorf("unsupported content format %q", item.ContentFormat)
+	}
+
+	item.Tags = models.NormalizeTags(item.Tags)
+	if len(item.Tags) > maxImportTags {
+		return fmt.Errorf("%d tags, at most %d are allowed", len(item.Tags), maxImportTags)
+	}
+	for _, tag := range item.Tags {
+		if utf8.RuneCountInString(tag) > maxImportTagName {
+			return fmt.Errorf("tag %q is longer than %d characters", tag, maxImportTagName)
+		}
+	}
+
+	// whole seconds, so a re-run finds the posts whatever precision the database keeps
+	item.CreatedAt = item.CreatedAt.UTC().Truncate(time.Second)
+	item.UpdatedAt = item.UpdatedAt.UTC().Truncate(time.Second)
+	if item.UpdatedAt.Before(item.CreatedAt) {
+		item.UpdatedAt = item.CreatedAt
+	}
+	return nil
+}
+
+// author finds the user of the item's author: the user it is mapped to, else
+// the user of the same username or email, else the default author.
+func (im *Importer) author(item ImportItem) (*models.User, error) {
+	if username, ok := im.Authors[item.Author]; ok {
+		user, err := im.findUser("username = ?", username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("author %q is mapped to unknown user %q", item.Author, username)
+		}
+		return user, err
+	}
+
+	if item.Author != "" {
+		user, err := im.findUser("username = ?", item.Author)
+		if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, err
+		}
+	}
+	if item.AuthorEmail != "" {
+		user, err := im.findUser("email = ?", item.AuthorEmail)
+		if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, err
+		}
+	}
+	if im.DefaultAuthor != "" {
+		return im.findUser("username = ?", im.DefaultAuthor)
+	}
+	switch {
+	case item.Author != "":
+		return nil, fmt.Errorf("unknown author %q", item.Author)
+	case item.AuthorEmail != "":
+		return nil, fmt.Errorf("unknown author email %q", item.AuthorEmail)
+	default:
+		return nil, errors.New("author is missing")
+	}
+}
+
+// findUser looks up a user who can author posts, remembering the users found.
+func (im *Importer) findUser(query, value string) (*models.User, error) {
+	key := query + value
+	if user, ok := im.users[key]; ok {
+		return user, nil
+	}
+
+	var user models.User
+	if err := im.DB.Where(query, value).Where("status <> ?", models.UserErased).First(&user).Error; err != nil {
+		return nil, err
+	}
+	im.users[key] = &user
+	return &user, nil
+}
+
+// assignSlug keeps the slug the post had in the source when it is free, so the
+// old permalinks keep their path, and else derives one from the title.
+func (im *Importer) assignSlug(tx *gorm.DB, post *models.Post, original string) error {
+	if unescaped, err := url.PathUnescape(original); err == nil {
+		original = unescaped
+	}
+	slug := utils.Slugify(original)
+	if slug == "" {
+		return models.AssignSlug(tx, post, im.AuthorScopedSlugs)
+	}
+
+	var scope uint
+	if im.AuthorScopedSlugs {
+		scope = post.UserID
+	}
+	var taken int64
+	if err := tx.Model(&models.PostSlug{}).Where("scope = ? AND slug = ?", scope, slug).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return models.AssignSlug(tx, post, im.AuthorScopedSlugs)
+	}
+
+	if err := tx.Create(&models.PostSlug{Scope: scope, Slug: slug, PostID: post.ID}).Error; err != nil {
+		return err
+	}
+	post.Slug = slug
+	return tx.Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumn("slug", slug).Error
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"blog-backend/models"
+	"blog-backend/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	jobPollInterval     = 2 * time.Second
+	jobDefaultAttempts  = 10
+	jobBaseBackoff      = 10 * time.Second
+	jobMaxBackoff       = time.Hour
+	jobDefaultWorkers   = 4
+	jobDefaultVisibTime = 5 * time.Minute
+)
+
+// JobHandler runs a job. A returned error schedules a retry.
+type JobHandler func(ctx context.Context, payload []byte) error
+
+type jobIDKey struct{}
+
+// JobID returns the ID of the job a handler runs, which stays the same across
+// retries, so the handler can tell the work of an earlier attempt.
+func JobID(ctx context.Context) uint {
+	id, _ := ctx.Value(jobIDKey{}).(uint)
+	return id
+}
+
+// JobQueue is a database-backed job queue. A claimed job is invisible to other
+// workers for the visibility timeout, after which a crashed worker's job is
+// picked up again, so handlers must be idempotent.
+type JobQueue struct {
+	DB                *gorm.DB
+	Workers           int
+	VisibilityTimeout time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+}
+
+func NewJobQueue(db *gorm.DB, workers int, visibilityTimeout time.Duration) *JobQueue {
+	if workers < 1 {
+		workers = jobDefaultWorkers
+	}
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = jobDefaultVisibTime
+	}
+
+	return &JobQueue{
+		DB:                db,
+		Workers:           workers,
+		VisibilityTimeout: visibilityTimeout,
+		handlers:          make(map[string]JobHandler),
+	}
+}
+
+// Register sets the handler of a job type.
+func (q *JobQueue) Register(jobType string, handler JobHandler) {
+	q.mu.Lock()
+	q.handlers[jobType] = handler
+	q.mu.Unlock()
+}
+
+// Enqueue inserts a job using tx, which should be the transaction of the
+// domain change so the job is committed or rolled back with it.
+func (q *JobQueue) Enqueue(tx *gorm.DB, jobType string, payload any) error {
+	return q.enqueue(tx, jobType, nil, payload)
+}
+
+// EnqueueFor is Enqueue for a payload that copies content of the user, so the
+// job is removed with the user's data when the account is erased.
+func (q *JobQueue) EnqueueFor(tx *gorm.DB, subjectID uint, jobType string, payload any) error {
+	return q.enqueue(tx, jobType, &subjectID, payload)
+}
+
+func (q *JobQueue) enqueue(tx *gorm.DB, jobType string, subjectID *uint, payload any) error {
+	job, err := newJob(jobType, payload)
+	if err != nil {
+		return err
+	}
+	job.SubjectID = subjectID
+	return tx.Create(job).Error
+}
+
+func newJob(jobType string, payload any) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      models.JobPending,
+		MaxAttempts: jobDefaultAttempts,
+		RunAt:       time.Now(),
+	}, nil
+}
+
+// Run starts the workers and blocks until the context is cancelled.
+func (q *JobQueue) Run(ctx context.Context) {
+	log.Printf("Job queue started with %d workers.", q.Workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < q.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	log.Printf("Job queue stopped.")
+}
+
+// Every enqueues a job of jobType at every interval until the context is
+// cancelled. A run is skipped while an earlier job of the type is still
+// pending or running, so several instances do not pile up work: the job has
+// its type as dedup key, and the insert of every instance but one conflicts.
+func (q *JobQueue) Every(ctx context.Context, jobType string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		job, err := newJob(jobType, struct{}{})
+		if err != nil {
+			log.Printf("Failed to schedule %s: %v", jobType, err)
+			continue
+		}
+		job.DedupKey = &jobType
+		if err := q.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(job).Error; err != nil {
+			log.Printf("Failed to schedule %s: %v", jobType, err)
+		}
+	}
+}
+
+func (q *JobQueue) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := q.claim()
+		if err != nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(jobPollInterval):
+			}
+			continue
+		}
+
+		q.execute(ctx, job)
+	}
+}
+
+// claim takes the next due job, or a running job whose visibility timeout
+// expired. It returns nil when there is nothing to do.
+func (q *JobQueue) claim() (*models.Job, error) {
+	for range 3 {
+		now := time.Now()
+
+		var job models.Job
+		err := q.DB.Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)",
+			models.JobPending, now, models.JobRunning, now).
+			Order("run_at").First(&job).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// the conditional update fails if another worker claimed it first
+		lockedUntil := now.Add(q.VisibilityTimeout)
+		result := q.DB.Model(&models.Job{}).
+			Where("id = ? AND status = ? AND attempts = ?", job.ID, job.Status, job.Attempts).
+			Updates(map[string]any{
+				"status":       models.JobRunning,
+				"attempts":     job.Attempts + 1,
+				"locked_until": lockedUntil,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = models.JobRunning
+			job.Attempts++
+			job.LockedUntil = &lockedUntil
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+func (q *JobQueue) execute(ctx context.Context, job *models.Job) {
+	q.mu.RLock()
+	handler, ok := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler registered for job type %s", job.Type)
+	} else {
+		jobCtx, cancel := context.WithTimeout(context.WithValue(ctx, jobIDKey{}, job.ID), q.VisibilityTimeout)
+		err = runJob(jobCtx, handler, []byte(job.Payload))
+		cancel()
+	}
+
+	now := time.Now()
+	updates := map[string]any{"locked_until": nil}
+	switch {
+	case err == nil:
+		updates["status"] = models.JobSucceeded
+		updates["finished_at"] = now
+		updates["last_error"] = ""
+		updates["dedup_key"] = nil
+	case job.Attempts >= job.MaxAttempts:
+		updates["status"] = models.JobDead
+		updates["finished_at"] = now
+		updates["dedup_key"] = nil
+		updates["last_error"] = utils.Truncate(err.Error(), 1024)
+		log.Printf("Job %d (%s) is dead after %d attempts: %v", job.ID, job.Type, job.Attempts, err)
+	default:
+		updates["status"] = models.JobPending
+		updates["run_at"] = now.Add(jobBackoff(job.Attempts))
+		updates["last_error"] = utils.Truncate(err.Error(), 1024)
+		log.Printf("Job %d (%s) failed, retrying: %v", job.ID, job.Type, err)
+	}
+
+	// only record the outcome if the job was not reclaimed after a timeout
+	if err := q.DB.Model(&models.Job{}).Where("id = ? AND status = ? AND attempts = ?", job.ID, models.JobRunning, job.Attempts).
+		Updates(updates).Error; err != nil {
+		log.Printf("Failed to record outcome of job %d: %v", job.ID, err)
+	}
+}
+
+// runJob runs the handler, turning a panic into an error so the worker survives.
+func runJob(ctx context.Context, handler JobHandler, payload []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, payload)
+}
+
+// jobBackoff doubles the delay after every failed attempt, capped at jobMaxBackoff.
+func jobBackoff(attempts int) time.Duration {
+	delay := jobBaseBackoff << (attempts - 1)
+	if delay <= 0 || delay > jobMaxBackoff {
+		return jobMaxBackoff
+	}
+	return delay
+}
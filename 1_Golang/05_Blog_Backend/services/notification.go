@@ -0,0 +1,142 @@
+package services
+
+import (
+	"blog-backend/models"
+	"errors"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// NotificationService creates notifications, honouring the recipient's preferences.
+type NotificationService struct {
+	DB  *gorm.DB
+	Hub *Hub
+}
+
+// NotifyComment tells the post author about a new comment and, for replies,
+// the parent commenter about the reply.
+func (s *NotificationService) NotifyComment(post *models.Post, comment *models.Comment) {
+	if comment.ParentID != nil {
+		var parent models.Comment
+		if err := s.DB.Select("id", "commenter_id").First(&parent, *comment.ParentID).Error; err == nil {
+			s.notify(models.Notification{
+				UserID:    parent.CommenterID,
+				ActorID:   comment.CommenterID,
+				Type:      models.NotifyReply,
+				PostID:    &post.ID,
+				CommentID: &comment.ID,
+			})
+			// the post author learns about the reply below unless they wrote the parent
+			if parent.CommenterID == post.UserID {
+				return
+			}
+		}
+	}
+
+	s.notify(models.Notification{
+		UserID:    post.UserID,
+		ActorID:   comment.CommenterID,
+		Type:      models.NotifyComment,
+		PostID:    &post.ID,
+		CommentID: &comment.ID,
+	})
+}
+
+// NotifyReaction tells the author of the post or comment about a reaction.
+func (s *NotificationService) NotifyReaction(reaction *models.Reaction) {
+	n := models.Notification{
+		ActorID: reaction.UserID,
+		Type:    models.NotifyReaction,
+		Detail:  reaction.Kind,
+	}
+
+	switch reaction.TargetType {
+	case models.TargetPost:
+		var post models.Post
+		if err := s.DB.Select("id", "user_id").First(&post, reaction.TargetID).Error; err != nil {
+			return
+		}
+		n.UserID, n.PostID = post.UserID, &post.ID
+	case models.TargetComment:
+		var comment models.Comment
+		if err := s.DB.Select("id", "commenter_id", "post_id").First(&comment, reaction.TargetID).Error; err != nil {
+			return
+		}
+		n.UserID, n.PostID, n.CommentID = comment.CommenterID, &comment.PostId, &comment.ID
+	default:
+		return
+	}
+
+	s.notify(n)
+}
+
+// NotifyFollow tells a user about a new follower.
+func (s *NotificationService) NotifyFollow(follow *models.Follow) {
+	s.notify(models.Notification{
+		UserID:  follow.FolloweeID,
+		ActorID: follow.FollowerID,
+		Type:    models.NotifyFollow,
+	})
+}
+
+// NotifyReportResolved tells the reporter whether the moderator actioned or
+// dismissed their report.
+func (s *NotificationService) NotifyReportResolved(report *models.Report) {
+	if report.ModeratorID == nil {
+		return
+	}
+
+	n := models.Notification{
+		UserID:  report.ReporterID,
+		ActorID: *report.ModeratorID,
+		Type:    models.NotifyReport,
+		Detail:  report.Status,
+	}
+
+	switch report.TargetType {
+	case models.TargetPost:
+		n.PostID = &report.TargetID
+	case models.TargetComment:
+		var comment models.Comment
+		if err := s.DB.Unscoped().Select("id", "post_id").First(&comment, report.TargetID).Error; err == nil {
+			n.PostID, n.CommentID = &comment.PostId, &comment.ID
+		}
+	}
+
+	s.notify(n)
+}
+
+// Preference returns the user's notification preference, the default when none was saved.
+func (s *NotificationService) Preference(userID uint) (models.NotificationPreference, error) {
+	pref := models.DefaultNotificationPreference(userID)
+	err := s.DB.First(&pref, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DefaultNotificationPreference(userID), nil
+	}
+	return pref, err
+}
+
+// notify stores the notification. Users are never notified about their own
+// actions, and failures are logged without failing the triggering request.
+func (s *NotificationService) notify(n models.Notification) {
+	if n.UserID == 0 || n.UserID == n.ActorID {
+		return
+	}
+
+	pref, err := s.Preference(n.UserID)
+	if err != nil {
+		log.Printf("Failed to load notification preference of user %d: %v", n.UserID, err)
+		return
+	}
+	if !pref.Wants(n.Type) {
+		return
+	}
+
+	if err := s.DB.Create(&n).Error; err != nil {
+		log.Printf("Failed to create %s notification for user %d: %v", n.Type, n.UserID, err)
+		return
+	}
+
+	s.Hub.Publish(UserTopic(n.UserID), Event{Type: EventNotification, Data: n})
+}
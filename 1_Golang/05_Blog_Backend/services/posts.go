@@ -0,0 +1,116 @@
+package services
+
+import (
+	"blog-backend/cache"
+	"blog-backend/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostReader loads posts the way readers see them: without posts hidden by
+// moderation, with the author and tags. The API, the feeds, the sitemap and
+// the static export all read through it so they agree on what is published.
+// The Cached methods read through Cache when it is set.
+type PostReader struct {
+	DB    *gorm.DB
+	Cache *cache.Store
+}
+
+// PostScope narrows the published posts, e.g. ByAuthor or Newest.
+type PostScope = func(*gorm.DB) *gorm.DB
+
+// ByAuthor limits the posts to one author.
+func ByAuthor(userID uint) PostScope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.user_id = ?", userID)
+	}
+}
+
+// ByTag limits the posts to those with the tag.
+func ByTag(tagID uint) PostScope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+			Table("post_tags").Select("post_id").Where("tag_id = ?", tagID))
+	}
+}
+
+// Newest orders the posts newest first, keeping at most limit (0 for all).
+func Newest(limit int) PostScope {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Order("posts.created_at desc, posts.id desc")
+		if limit > 0 {
+			db = db.Limit(limit)
+		}
+		return db
+	}
+}
+
+// List returns the published posts matching the scopes.
+func (r *PostReader) List(scopes ...PostScope) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.DB.Scopes(models.VisiblePosts).Scopes(scopes...).Joins("User").Preload("Tags").Find(&posts).Error
+	return posts, err
+}
+
+// Get returns a published post with its visible comments and their authors.
+func (r *PostReader) Get(postID uint) (*models.Post, error) {
+	var post models.Post
+	err := r.DB.Scopes(models.VisiblePosts).Joins("User").Preload("Tags").
+		Preload("Comments", models.VisibleComments).Preload("Comments.Commenter").
+		First(&post, postID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// CachedList is List through the cache, key naming the scopes.
+func (r *PostReader) CachedList(ctx context.Context, key string, scopes ...PostScope) ([]models.Post, error) {
+	return cache.Fetch(ctx, r.Cache, r.Cache.Key(ctx, CachePosts, "list", key), func() ([]models.Post, error) {
+		return r.List(scopes...)
+	})
+}
+
+// CachedGet is Get through the cache. Cached posts went through JSON, so the
+// comments have no Commenter.
+func (r *PostReader) CachedGet(ctx context.Context, postID uint) (*models.Post, error) {
+	return cache.Fetch(ctx, r.Cache, r.Cache.Key(ctx, CachePosts, "post", postID), func() (*models.Post, error) {
+		return r.Get(postID)
+	})
+}
+
+// PostLink is the minimum needed to link to a published post.
+type PostLink struct {
+	ID        uint
+	Slug      string
+	UserID    uint
+	UpdatedAt time.Time
+}
+
+// TagLink is a tag of a published post.
+type TagLink struct {
+	PostID uint
+	Name   string
+}
+
+// Links returns every published post without content, oldest first, and the
+// tags they carry, for listings that cover the whole site.
+func (r *PostReader) Links() ([]PostLink, []TagLink, error) {
+	var posts []PostLink
+	if err := r.DB.Model(&models.Post{}).Scopes(models.VisiblePosts).
+		Select("posts.id", "posts.slug", "posts.user_id", "posts.updated_at").
+		Order("posts.id").Find(&posts).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var tags []TagLink
+	err := r.DB.Table("post_tags").
+		Select("post_tags.post_id, tags.name").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Joins("JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL").
+		Scopes(models.VisiblePosts).
+		Order("tags.name").Find(&tags).Error
+	return posts, tags, err
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"blog-backend/models"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// sitemapLimit is the most URLs a single sitemap file may list.
+const sitemapLimit = 50000
+
+// SitemapURL is an entry of sitemap.xml.
+type SitemapURL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+// Sitemap lists the public pages of the blog: the home page, every published
+// post and the author and tag pages, each modified when its newest post was.
+type Sitemap struct {
+	URLs    []SitemapURL
+	Updated time.Time
+}
+
+// AuthorPath and TagPath are the site paths of the author and tag listings.
+func AuthorPath(userID uint) string { return fmt.Sprintf("/users/%d", userID) }
+func TagPath(name string) string    { return "/tags/" + url.PathEscape(name) }
+
+// BuildSitemap collects the sitemap entries of the published posts.
+func BuildSitemap(reader *PostReader, siteURL string) (*Sitemap, error) {
+	posts, tags, err := reader.Links()
+	if err != nil {
+		return nil, err
+	}
+
+	sitemap := &Sitemap{}
+	authors := map[uint]time.Time{}
+	updated := make(map[uint]time.Time, len(posts))
+	var postURLs []SitemapURL
+	for _, link := range posts {
+		postURLs = append(postURLs, SitemapURL{
+			Loc:     PostPermalink(siteURL, models.Post{ID: link.ID, Slug: link.Slug}),
+			LastMod: link.UpdatedAt,
+		})
+		updated[link.ID] = link.UpdatedAt
+		authors[link.UserID] = latest(authors[link.UserID], link.UpdatedAt)
+		sitemap.Updated = latest(sitemap.Updated, link.UpdatedAt)
+	}
+
+	tagged := map[string]time.Time{}
+	for _, tag := range tags {
+		tagged[tag.Name] = latest(tagged[tag.Name], updated[tag.PostID])
+	}
+
+	sitemap.URLs = append(sitemap.URLs, SitemapURL{Loc: siteURL + "/", LastMod: sitemap.Updated})
+	sitemap.URLs = append(sitemap.URLs, postURLs...)
+	for _, id := range sortedKeys(authors) {
+		sitemap.URLs = append(sitemap.URLs, SitemapURL{Loc: siteURL + AuthorPath(id), LastMod: authors[id]})
+	}
+	for _, name := range sortedKeys(tagged) {
+		sitemap.URLs = append(sitemap.URLs, SitemapURL{Loc: siteURL + TagPath(name), LastMod: tagged[name]})
+	}
+
+	if len(sitemap.URLs) > sitemapLimit {
+		log.Printf("Sitemap has %d URLs, only the first %d are listed", len(sitemap.URLs), sitemapLimit)
+		sitemap.URLs = sitemap.URLs[:sitemapLimit]
+	}
+	return sitemap, nil
+}
+
+type sitemapDocument struct {
+	XMLName xml.Name          `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapDocEntry `xml:"url"`
+}
+
+type sitemapDocEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Write renders the sitemap as sitemaps.org XML.
+func (s *Sitemap) Write(w io.Writer) error {
+	doc := sitemapDocument{URLs: make([]sitemapDocEntry, 0, len(s.URLs))}
+	for _, u := range s.URLs {
+		entry := sitemapDocEntry{Loc: u.Loc}
+		if !u.LastMod.IsZero() {
+			entry.LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+		doc.URLs = append(doc.URLs, entry)
+	}
+	return writeXML(w, doc)
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+func sortedKeys[K uint | string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"blog-backend/models"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Syndication feed formats, named after the extension they are served with.
+const (
+	FeedRSS  = "rss"
+	FeedAtom = "atom"
+	FeedJSON = "json"
+)
+
+// FeedContentTypes maps each feed format to its Content-Type.
+var FeedContentTypes = map[string]string{
+	FeedRSS:  "application/rss+xml; charset=utf-8",
+	FeedAtom: "application/atom+xml; charset=utf-8",
+	FeedJSON: "application/feed+json; charset=utf-8",
+}
+
+// FeedSize is how many of the newest posts a feed carries.
+const FeedSize = 20
+
+// Syndication is a feed of posts, independent of the format it is rendered in.
+// Posts are expected newest first, with User and Tags loaded.
+type Syndication struct {
+	Title       string
+	Description string
+	HomeURL     string
+	FeedURL     string
+	Updated     time.Time
+	Posts       []models.Post
+}
+
+// SiteFeed describes the feed of all posts on the site.
+func SiteFeed(siteTitle, siteURL string) *Syndication {
+	return &Syndication{
+		Title:       siteTitle,
+		Description: "Latest posts on " + siteTitle,
+		HomeURL:     siteURL + "/",
+	}
+}
+
+// PostPermalink is the public URL of the post, by slug when it has one. The
+// API does not serve /posts/ itself, the path is that of the page written by
+// the static export, or of a front end at the site URL.
+func PostPermalink(siteURL string, post models.Post) string {
+	if post.Slug == "" {
+		return siteURL + "/posts/" + strconv.FormatUint(uint64(post.ID), 10)
+	}
+	return siteURL + "/posts/" + url.PathEscape(post.Slug)
+}
+
+// RenderFeed writes the feed in the given format.
+func RenderFeed(w io.Writer, format string, feed *Syndication, siteURL string) error {
+	switch format {
+	case FeedRSS:
+		return writeXML(w, rssFeed(feed, siteURL))
+	case FeedAtom:
+		return writeXML(w, atomFeed(feed, siteURL))
+	case FeedJSON:
+		return json.NewEncoder(w).Encode(jsonFeed(feed, siteURL))
+	default:
+		return fmt.Errorf("unknown feed format %q", format)
+	}
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// RSS 2.0, with the author in dc:creator since <author> must be an email address.
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func rssFeed(feed *Syndication, siteURL string) *rssDocument {
+	doc := &rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       feed.Title,
+			Link:        feed.HomeURL,
+			Description: feed.Description,
+			Self:        atomLink{Href: feed.FeedURL, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	if !feed.Updated.IsZero() {
+		doc.Channel.LastBuildDate = feed.Updated.Format(time.RFC1123Z)
+	}
+
+	for _, post := range feed.Posts {
+		link := PostPermalink(siteURL, post)
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       post.Title,
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			PubDate:     post.CreatedAt.Format(time.RFC1123Z),
+			Creator:     post.User.Username,
+			Categories:  tagNames(post.Tags),
+			Description: post.ContentHTML,
+		})
+	}
+	return doc
+}
+
+// Atom 1.0 (RFC 4287).
+type atomDocument struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomAuthor     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func atomFeed(feed *Syndication, siteURL string) *atomDocument {
+	// updated is required, an empty feed has not changed since the epoch
+	updated := feed.Updated
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+	doc := &atomDocument{
+		ID:      feed.FeedURL,
+		Title:   feed.Title,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: feed.FeedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: feed.HomeURL, Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	for _, post := range feed.Posts {
+		link := PostPermalink(siteURL, post)
+		entry := atomEntry{
+			ID:        link,
+			Title:     post.Title,
+			Link:      atomLink{Href: link, Rel: "alternate", Type: "text/html"},
+			Published: post.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   post.UpdatedAt.UTC().Format(time.RFC3339),
+			Author:    atomAuthor{Name: post.User.Username},
+			Content:   atomContent{Type: "html", Value: post.ContentHTML},
+		}
+		for _, tag := range post.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag.Name})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return doc
+}
+
+// JSON Feed 1.1 (https://jsonfeed.org/version/1.1).
+type jsonFeedDocument struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+func jsonFeed(feed *Syndication, siteURL string) *jsonFeedDocument {
+	doc := &jsonFeedDocument{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageURL: feed.HomeURL,
+		FeedURL:     feed.FeedURL,
+		Description: feed.Description,
+		Items:       []jsonFeedItem{},
+	}
+
+	for _, post := range feed.Posts {
+		doc.Items = append(doc.Items, jsonFeedItem{
+			ID:            strconv.FormatUint(uint64(post.ID), 10),
+			URL:           PostPermalink(siteURL, post),
+			Title:         post.Title,
+			ContentHTML:   post.ContentHTML,
+			DatePublished: post.CreatedAt.Format(time.RFC3339),
+			DateModified:  post.UpdatedAt.Format(time.RFC3339),
+			Authors:       []jsonFeedAuthor{{Name: post.User.Username}},
+			Tags:          tagNames(post.Tags),
+		})
+	}
+	return doc
+}
+
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
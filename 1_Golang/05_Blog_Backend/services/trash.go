@@ -0,0 +1,196 @@
+package services
+
+import (
+	"blog-backend/models"
+	"blog-backend/storage"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// JobPurgeTrash is the job type that hard-deletes expired trash.
+	JobPurgeTrash = "trash.purge"
+	// TrashPurgeInterval is how often the purge job is scheduled.
+	TrashPurgeInterval = time.Hour
+
+	purgeBatchSize = 100
+)
+
+// PurgeResult counts what a purge removed for good.
+type PurgeResult struct {
+	Posts       int `json:"posts"`
+	Comments    int `json:"comments"`
+	Attachments int `json:"attachments"`
+}
+
+// PurgeTrash hard-deletes posts, comments and attachments that were deleted
+// before the cutoff, together with the rows that reference them. Stored files
+// are removed only after their rows are gone, so a failed transaction never
+// leaves rows pointing at missing objects.
+func PurgeTrash(ctx context.Context, db *gorm.DB, store storage.Storage, before time.Time) (PurgeResult, error) {
+	db = db.WithContext(ctx)
+	var result PurgeResult
+	var keys []string
+	defer func() { deleteObjects(ctx, store, keys) }()
+
+	for {
+		var postIDs []uint
+		if err := db.Unscoped().Model(&models.Post{}).Where("deleted_at < ?", before).
+			Limit(purgeBatchSize).Pluck("id", &postIDs).Error; err != nil {
+			return result, err
+		}
+
+		for _, postID := range postIDs {
+			var postKeys []string
+			err := db.Transaction(func(tx *gorm.DB) (err error) {
+				postKeys, err = purgePost(tx, postID)
+				return err
+			})
+			if err != nil {
+				return result, err
+			}
+			keys = append(keys, postKeys...)
+			result.Posts++
+		}
+		if len(postIDs) < purgeBatchSize {
+			break
+		}
+	}
+
+	for {
+		var commentIDs []uint
+		if err := db.Unscoped().Model(&models.Comment{}).Where("deleted_at < ?", before).
+			Limit(purgeBatchSize).Pluck("id", &commentIDs).Error; err != nil {
+			return result, err
+		}
+
+		if len(commentIDs) > 0 {
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				return purgeComments(tx, commentIDs)
+			}); err != nil {
+				return result, err
+			}
+			result.Comments += len(commentIDs)
+		}
+		if len(commentIDs) < purgeBatchSize {
+			break
+		}
+	}
+
+	var attachments []models.Attachment
+	if err := db.Unscoped().Where("deleted_at < ?", before).Find(&attachments).Error; err != nil {
+		return result, err
+	}
+	for _, attachment := range attachments {
+		if err := db.Unscoped().Delete(&attachment).Error; err != nil {
+			return result, err
+		}
+		keys = append(keys, attachmentKeys(attachment)...)
+		result.Attachments++
+	}
+
+	return result, nil
+}
+
+// purgePost removes the post, its comments and everything attached to it, and
+// returns the storage keys of its attachments.
+func purgePost(tx *gorm.DB, postID uint) ([]string, error) {
+	var post models.Post
+	if err := tx.Unscoped().First(&post, postID).Error; err != nil {
+		return nil, err
+	}
+
+	var commentIDs []uint
+	if err := tx.Unscoped().Model(&models.Comment{}).Where("post_id = ?", post.ID).Pluck("id", &commentIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := purgeComments(tx, commentIDs); err != nil {
+		return nil, err
+	}
+
+	var attachments []models.Attachment
+	if err := tx.Unscoped().Where("post_id = ?", post.ID).Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, attachment := range attachments {
+		keys = append(keys, attachmentKeys(attachment)...)
+	}
+
+	for _, model := range []any{&models.Bookmark{}, &models.PostRevision{}, &models.PostSlug{}, &models.PostDailyView{}, &models.Notification{}} {
+		if err := tx.Where("post_id = ?", post.ID).Delete(model).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Unscoped().Where("post_id = ?", post.ID).Delete(&models.Attachment{}).Error; err != nil {
+		return nil, err
+	}
+	if err := purgeReactions(tx, models.TargetPost, []uint{post.ID}); err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&post).Association("Tags").Clear(); err != nil {
+		return nil, err
+	}
+
+	return keys, tx.Unscoped().Delete(&post).Error
+}
+
+// purgeComments removes the comments with their reactions and notifications.
+// Replies to them stay and become top-level comments.
+func purgeComments(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := purgeReactions(tx, models.TargetComment, ids); err != nil {
+		return err
+	}
+	if err := tx.Where("comment_id IN ?", ids).Delete(&models.Notification{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&models.Comment{}).Where("parent_id IN ? AND id NOT IN ?", ids, ids).
+		UpdateColumn("parent_id", nil).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Comment{}).Error
+}
+
+func purgeReactions(tx *gorm.DB, targetType string, ids []uint) error {
+	if err := tx.Where("target_type = ? AND target_id IN ?", targetType, ids).Delete(&models.Reaction{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("target_type = ? AND target_id IN ?", targetType, ids).Delete(&models.ReactionCount{}).Error
+}
+
+func attachmentKeys(attachment models.Attachment) []string {
+	if attachment.ThumbnailKey == "" {
+		return []string{attachment.StorageKey}
+	}
+	return []string{attachment.StorageKey, attachment.ThumbnailKey}
+}
+
+func deleteObjects(ctx context.Context, store storage.Storage, keys []string) {
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Failed to delete stored object %s: %v", key, err)
+		}
+	}
+}
+
+// PurgeJob returns the handler of JobPurgeTrash, which purges trash older than retention.
+func PurgeJob(db *gorm.DB, store storage.Storage, retention time.Duration) JobHandler {
+	return func(ctx context.Context, payload []byte) error {
+		result, err := PurgeTrash(ctx, db, store, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		if result != (PurgeResult{}) {
+			log.Printf("Purged trash: %d posts, %d comments, %d attachments", result.Posts, result.Comments, result.Attachments)
+		}
+		return nil
+	}
+}
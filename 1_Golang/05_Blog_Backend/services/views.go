@@ -0,0 +1,140 @@
+package services
+
+import (
+	"blog-backend/cache"
+	"blog-backend/models"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	viewDefaultWindow        = 30 * time.Minute
+	viewDefaultFlushInterval = 10 * time.Second
+)
+
+// ViewTracker counts post views. A viewer, a user or an IP address, is counted
+// once per post within Window. Counted views are buffered in memory and Run
+// writes them every FlushInterval, one update per post and day instead of one
+// per view, so views buffered when the process dies are lost.
+type ViewTracker struct {
+	DB            *gorm.DB
+	Seen          cache.Cache
+	Window        time.Duration
+	FlushInterval time.Duration
+
+	mu      sync.Mutex
+	pending map[viewKey]int64
+}
+
+type viewKey struct {
+	PostID uint
+	Day    time.Time
+}
+
+// NewViewTracker remembers viewers in seen, which must be shared by every
+// instance for views to be deduplicated across them.
+func NewViewTracker(db *gorm.DB, seen cache.Cache, window, flushInterval time.Duration) *ViewTracker {
+	if window <= 0 {
+		window = viewDefaultWindow
+	}
+	if flushInterval <= 0 {
+		flushInterval = viewDefaultFlushInterval
+	}
+
+	return &ViewTracker{
+		DB:            db,
+		Seen:          seen,
+		Window:        window,
+		FlushInterval: flushInterval,
+		pending:       make(map[viewKey]int64),
+	}
+}
+
+// Record counts a view of the post unless the viewer viewed it within the window.
+func (t *ViewTracker) Record(ctx context.Context, postID uint, viewer string) {
+	if t == nil {
+		return
+	}
+
+	// the check and the set are one step, so concurrent requests count once
+	key := fmt.Sprintf("view:%d:%s", postID, viewer)
+	if added, err := t.Seen.Add(ctx, key, []byte{1}, t.Window); err != nil {
+		log.Printf("View dedup of %s failed: %v", key, err)
+	} else if !added {
+		return
+	}
+
+	t.add(viewKey{PostID: postID, Day: today()}, 1)
+}
+
+func (t *ViewTracker) add(key viewKey, views int64) {
+	t.mu.Lock()
+	t.pending[key] += views
+	t.mu.Unlock()
+}
+
+// Run flushes the buffered views every FlushInterval until the context is
+// cancelled, and once more before it returns.
+func (t *ViewTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := t.Flush(context.Background()); err != nil {
+				log.Printf("Failed to flush views: %v", err)
+			}
+			return
+		case <-ticker.C:
+		}
+
+		if err := t.Flush(ctx); err != nil {
+			log.Printf("Failed to flush views: %v", err)
+		}
+	}
+}
+
+// Flush writes the buffered views to the posts' view counts and daily views.
+// Views that could not be written are kept for the next flush.
+func (t *ViewTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[viewKey]int64)
+	t.mu.Unlock()
+
+	// cached posts show view counts up to the cache TTL old
+	db := t.DB.WithContext(ctx).Set(SkipCacheInvalidation, true)
+
+	var failed error
+	for key, views := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.Post{}).Where("id = ?", key.PostID).
+				UpdateColumn("view_count", gorm.Expr("view_count + ?", views))
+			if result.Error != nil || result.RowsAffected == 0 {
+				// the post was deleted since it was viewed
+				return result.Error
+			}
+			return tx.Clauses(clause.OnConflict{
+				DoUpdates: clause.Assignments(map[string]any{"views": gorm.Expr("views + ?", views)}),
+			}).Create(&models.PostDailyView{PostID: key.PostID, Day: key.Day, Views: views}).Error
+		})
+		if err != nil {
+			t.add(key, views)
+			failed = err
+		}
+	}
+	return failed
+}
+
+// today is the start of the current day, the day views are counted on.
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"blog-backend/models"
+	"blog-backend/utils"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	webhookPollInterval = 5 * time.Second
+	webhookBatchSize    = 20
+	webhookMaxAttempts  = 8
+	webhookBaseBackoff  = 30 * time.Second
+	webhookMaxBackoff   = 6 * time.Hour
+	// webhookLease keeps other workers off a delivery while it is being sent.
+	webhookLease = 2 * time.Minute
+)
+
+// WebhookPayload is the JSON body posted to webhook endpoints.
+type WebhookPayload struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// JobWebhookFanOut is the job type that turns an event into webhook deliveries.
+const JobWebhookFanOut = "webhook.fan_out"
+
+// WebhookService queues events for matching webhooks and delivers them in the background.
+type WebhookService struct {
+	DB     *gorm.DB
+	Jobs   *JobQueue
+	Client *http.Client
+}
+
+// NewWebhookService creates the service. Unless allowPrivate is set, deliveries
+// to loopback and private addresses are refused so webhooks cannot probe the
+// internal network. The fan-out job handler is registered on jobs.
+func NewWebhookService(db *gorm.DB, jobs *JobQueue, allowPrivate bool) *WebhookService {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+				return fmt.Errorf("webhook address %s is not allowed", host)
+			}
+			return nil
+		}
+	}
+
+	s := &WebhookService{
+		DB:   db,
+		Jobs: jobs,
+		Client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	jobs.Register(JobWebhookFanOut, s.fanOut)
+
+	return s
+}
+
+// Enqueue queues the event as a job using tx, the transaction of the change
+// that caused it. The job fans the event out to every active webhook of the
+// owner and every global webhook that subscribed to it. A nil service ignores
+// events.
+func (s *WebhookService) Enqueue(tx *gorm.DB, event string, ownerID uint, data any) error {
+	if s == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(WebhookPayload{Event: event, CreatedAt: time.Now(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	subjectID := payloadAuthor(ownerID, data)
+	return s.Jobs.EnqueueFor(tx, subjectID, JobWebhookFanOut,
+		webhookFanOut{Event: event, OwnerID: ownerID, SubjectID: subjectID, Payload: payload})
+}
+
+// payloadAuthor returns the author of the content in an event, the commenter
+// for comments and the owner for everything else.
+func payloadAuthor(ownerID uint, data any) uint {
+	switch data := data.(type) {
+	case models.Comment:
+		return data.CommenterID
+	case *models.Comment:
+		return data.CommenterID
+	}
+	return ownerID
+}
+
+// webhookFanOut is the payload of a JobWebhookFanOut job.
+type webhookFanOut struct {
+	Event     string          `json:"event"`
+	OwnerID   uint            `json:"owner_id"`
+	SubjectID uint            `json:"subject_id"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// fanOut creates a delivery for every webhook that wants the event. A
+// delivery is unique per job and webhook, so the deliveries an earlier
+// attempt of the job created are not created again.
+func (s *WebhookService) fanOut(ctx context.Context, data []byte) error {
+	var job webhookFanOut
+	if err := json.Unmarshal(data, &job); err != nil {
+		return err
+	}
+
+	// global webhooks only receive events while their owner is still an admin
+	var webhooks []models.Webhook
+	if err := s.DB.WithContext(ctx).Joins("JOIN users ON users.id = webhooks.user_id").
+		Where("webhooks.active = ? AND (webhooks.user_id = ? OR (webhooks.global = ? AND users.role = ?))",
+			true, job.OwnerID, true, models.RoleAdmin).
+		Find(&webhooks).Error; err != nil {
+		return err
+	}
+
+	jobID := JobID(ctx)
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, webhook := range webhooks {
+			if !webhook.Wants(job.Event) {
+				continue
+			}
+			delivery := models.WebhookDelivery{
+				WebhookID:     webhook.ID,
+				JobID:         &jobID,
+				Event:         job.Event,
+				Payload:       string(job.Payload),
+				SubjectID:     job.SubjectID,
+				Status:        models.DeliveryPending,
+				NextAttemptAt: time.Now(),
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&delivery).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Run delivers due webhooks until the context is cancelled.
+func (s *WebhookService) Run(ctx context.Context) {
+	log.Printf("Webhook dispatcher started.")
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Webhook dispatcher stopped.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *WebhookService) deliverDue(ctx context.Context) {
+	var due []models.WebhookDelivery
+	if err := s.DB.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+		Order("next_attempt_at").Limit(webhookBatchSize).Find(&due).Error; err != nil {
+		log.Printf("Failed to load due webhook deliveries: %v", err)
+		return
+	}
+
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// claim the delivery, another worker may have taken it in the meantime
+		claim := s.DB.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, models.DeliveryPending, delivery.NextAttemptAt).
+			Update("next_attempt_at", time.Now().Add(webhookLease))
+		if claim.Error != nil || claim.RowsAffected == 0 {
+			continue
+		}
+
+		s.Deliver(ctx, &delivery)
+	}
+}
+
+// Deliver sends the delivery once and records the outcome, scheduling a retry on failure.
+func (s *WebhookService) Deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	var webhook models.Webhook
+	if err := s.DB.First(&webhook, delivery.WebhookID).Error; err != nil || !webhook.Active {
+		s.DB.Model(delivery).Updates(map[string]any{"status": models.DeliveryDead, "last_error": "webhook removed or inactive"})
+		return
+	}
+
+	statusCode, err := s.send(ctx, &webhook, delivery)
+
+	updates := map[string]any{
+		"attempts":         delivery.Attempts + 1,
+		"last_status_code": statusCode,
+		"last_error":       "",
+	}
+
+	if err == nil {
+		now := time.Now()
+		updates["status"] = models.DeliverySucceeded
+		updates["delivered_at"] = now
+	} else {
+		updates["last_error"] = utils.Truncate(err.Error(), 1024)
+		if delivery.Attempts+1 >= webhookMaxAttempts {
+			updates["status"] = models.DeliveryDead
+			log.Printf("Webhook delivery %d is dead after %d attempts: %v", delivery.ID, delivery.Attempts+1, err)
+		} else {
+			updates["next_attempt_at"] = time.Now().Add(webhookBackoff(delivery.Attempts + 1))
+		}
+	}
+
+	if err := s.DB.Model(delivery).Updates(updates).Error; err != nil {
+		log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func (s *WebhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "blog-backend-webhooks")
+	req.Header.Set("X-Blog-Event", delivery.Event)
+	req.Header.Set("X-Blog-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Blog-Timestamp", timestamp)
+	req.Header.Set("X-Blog-Signature", "sha256="+SignWebhook(webhook.Secret, timestamp, body))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, errors.New("endpoint returned status " + resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of "timestamp.body" keyed with the
+// secret. Receivers recompute it to verify the payload and reject old timestamps.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff doubles the delay after every failed attempt, capped at webhookMaxBackoff.
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseBackoff << (attempts - 1)
+	if delay <= 0 || delay > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return delay
+}
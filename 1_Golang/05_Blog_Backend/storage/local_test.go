@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "uploads")
+	s, err := NewLocalStorage(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "attachments/1/a.txt", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.Get(ctx, "/attachments/1/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(r)
+	r.Close()
+	if string(body) != "hello" {
+		t.Errorf("Get = %q, want %q", body, "hello")
+	}
+
+	if err := s.Delete(ctx, "attachments/1/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "attachments/1/a.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "attachments/1/a.txt"); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(root, "attachments", "1"))
+	if len(entries) != 0 {
+		t.Errorf("temporary files are left behind: %v", entries)
+	}
+}
+
+func TestLocalStorageRejectsEscapingKeys(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "/", "../outside.txt", "a/../../outside.txt"} {
+		if err := s.Put(context.Background(), key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+	}
+}
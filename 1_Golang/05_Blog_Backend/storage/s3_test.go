@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a local stand-in for an S3 bucket. It checks the signature of
+// every request against the request as it arrived, so a path encoded
+// differently on the wire than it was signed is caught.
+type fakeS3 struct {
+	t         *testing.T
+	bucket    string
+	secretKey string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeS3(t *testing.T, bucket, secretKey string) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{t: t, bucket: bucket, secretKey: secretKey, objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.verify(r) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	prefix := "/" + f.bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if r.ContentLength != int64(len(body)) {
+			http.Error(w, "IncompleteBody", http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		if _, ok := f.objects[key]; !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify recomputes the Signature Version 4 signature from the received request.
+func (f *fakeS3) verify(r *http.Request) bool {
+	var credential, signedHeaders, signature string
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	for _, part := range strings.Split(auth, ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		switch name {
+		case "Credential":
+			credential = value
+		case "SignedHeaders":
+			signedHeaders = value
+		case "Signature":
+			signature = value
+		}
+	}
+	fields := strings.SplitN(credential, "/", 2)
+	if len(fields) != 2 || !strings.Contains(signedHeaders, "host") {
+		f.t.Errorf("%s %s: malformed Authorization %q", r.Method, r.URL, r.Header.Get("Authorization"))
+		return false
+	}
+	scope := fields[1]
+
+	var canonicalHeaders strings.Builder
+	names := strings.Split(signedHeaders, ";")
+	if !sort.StringsAreSorted(names) {
+		f.t.Errorf("signed headers %q are not sorted", signedHeaders)
+	}
+	for _, name := range names {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		canonicalQuery(r.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", r.Header.Get("X-Amz-Date"), scope, hexSHA256(canonicalRequest)}, "\n")
+
+	key := []byte("AWS4" + f.secretKey)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+	if want := hex.EncodeToString(hmacSHA256(key, stringToSign)); signature != want {
+		f.t.Errorf("%s %s: signature %s, want %s", r.Method, r.URL.EscapedPath(), signature, want)
+		return false
+	}
+	return true
+}
+
+func TestS3Storage(t *testing.T) {
+	fake, server := newFakeS3(t, "uploads", "secret")
+	s, err := NewS3Storage(server.URL, "", "uploads", "access", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"attachments/1/photo.png", "attachments/2/a file (1)+語.txt"} {
+		body := []byte("content of " + key)
+		if err := s.Put(ctx, key, bytes.NewReader(body), int64(len(body)), "text/plain"); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+		if got := fake.types[key]; got != "text/plain" {
+			t.Errorf("Put(%q) stored content type %q", key, got)
+		}
+
+		r, err := s.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", key, err)
+		}
+		got, _ := io.ReadAll(r)
+		r.Close()
+		if !bytes.Equal(got, body) {
+			t.Errorf("Get(%q) = %q, want %q", key, got, body)
+		}
+
+		if err := s.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete(%q): %v", key, err)
+		}
+		if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) after Delete: %v, want ErrNotFound", key, err)
+		}
+	}
+
+	if err := s.Delete(ctx, "missing"); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+}
+
+func TestS3StorageErrors(t *testing.T) {
+	if _, err := NewS3Storage("", "", "uploads", "", ""); err == nil {
+		t.Error("NewS3Storage without an endpoint succeeded")
+	}
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+	}))
+	defer rejecting.Close()
+	s, err := NewS3Storage(rejecting.URL, "", "uploads", "access", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "a.txt", strings.NewReader("x"), 1, ""); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Put against a rejecting server: %v, want the error body", err)
+	}
+	if _, err := s.Get(ctx, "a.txt"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get against a rejecting server: %v", err)
+	}
+	if err := s.Delete(ctx, "a.txt"); err == nil {
+		t.Error("Delete against a rejecting server succeeded")
+	}
+}
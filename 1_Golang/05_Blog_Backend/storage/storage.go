@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"blog-backend/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+)
+
+// ErrNotFound is returned when an object does not exist.
+var ErrNotFound = errors.New("Object not found.")
+
+// Storage stores uploaded files by key.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromConfig creates the storage selected by STORAGE_DRIVER, local is the default.
+func NewFromConfig(cfg *config.Config) (Storage, error) {
+	switch cfg.StorageDriver {
+	case "", "local":
+		log.Printf("Using local storage at %s", cfg.StorageLocalDir)
+		return NewLocalStorage(cfg.StorageLocalDir)
+	case "s3":
+		log.Printf("Using S3 storage at %s, bucket %s", cfg.S3Endpoint, cfg.S3Bucket)
+		return NewS3Storage(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey)
+	default:
+		return nil, fmt.Errorf("Unknown storage driver %q.", cfg.StorageDriver)
+	}
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"bytes"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Content formats of posts and comments.
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+	// FormatHTML is only set by imports from other blogs, and not accepted by the API.
+	FormatHTML = "html"
+)
+
+// raw HTML in the source is omitted by goldmark unless html.WithUnsafe is set
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// RenderContent renders the content in the given format to HTML sanitized with the policy.
+func RenderContent(content, format string, policy *SanitizePolicy) string {
+	if format == FormatHTML {
+		return SanitizeHTML(renderHTML(content), policy)
+	}
+	if format != FormatMarkdown {
+		return SanitizeHTML(renderPlain(content), policy)
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(content), &buf); err != nil {
+		log.Printf("Markdown rendering failed, falling back to plain text: %v", err)
+		return SanitizeHTML(renderPlain(content), policy)
+	}
+
+	return SanitizeHTML(buf.String(), policy)
+}
+
+// renderPlain escapes the text, turning blank-line separated blocks into paragraphs.
+func renderPlain(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var sb strings.Builder
+	for _, block := range strings.Split(content, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		sb.WriteString("<p>")
+		sb.WriteString(strings.ReplaceAll(html.EscapeString(block), "\n", "<br>"))
+		sb.WriteString("</p>\n")
+	}
+
+	return sb.String()
+}
+
+// paragraphTag finds the paragraphs of HTML that has them.
+var paragraphTag = regexp.MustCompile(`(?i)<p[\s>]`)
+
+// blockTag matches the start of a block that needs no paragraph around it.
+var blockTag = regexp.MustCompile(`(?i)^<(/?)(p|div|h[1-6]|ul|ol|li|blockquote|pre|table|figure|hr|dl|section|!--)[\s>/]?`)
+
+// renderHTML turns the blank-line separated blocks of HTML without paragraphs
+// into paragraphs, as WordPress stores classic editor content that way.
+func renderHTML(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if paragraphTag.MatchString(content) {
+		return content
+	}
+
+	var sb strings.Builder
+	for _, block := range strings.Split(content, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		if blockTag.MatchString(block) {
+			sb.WriteString(block)
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString("<p>")
+		sb.WriteString(strings.ReplaceAll(block, "\n", "<br>\n"))
+		sb.WriteString("</p>\n")
+	}
+
+	return sb.String()
+}
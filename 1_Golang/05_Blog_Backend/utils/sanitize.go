@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// SanitizePolicy is an allow-list of tags and, per tag, their allowed attributes.
+type SanitizePolicy struct {
+	Tags map[string][]string
+}
+
+// PostPolicy allows the markup produced by rendering post Markdown.
+var PostPolicy = &SanitizePolicy{Tags: map[string][]string{
+	"p": nil, "br": nil, "hr": nil, "blockquote": nil,
+	"h1": {"id"}, "h2": {"id"}, "h3": {"id"}, "h4": {"id"}, "h5": {"id"}, "h6": {"id"},
+	"strong": nil, "em": nil, "del": nil, "code": {"class"}, "pre": nil,
+	"ul": nil, "ol": {"start"}, "li": nil,
+	"a":     {"href", "title"},
+	"img":   {"src", "alt", "title"},
+	"table": nil, "thead": nil, "tbody": nil, "tr": nil, "th": {"align"}, "td": {"align"},
+	"input": {"type", "checked", "disabled"},
+}}
+
+// CommentPolicy allows inline formatting, links and code only.
+var CommentPolicy = &SanitizePolicy{Tags: map[string][]string{
+	"p": nil, "br": nil, "blockquote": nil,
+	"strong": nil, "em": nil, "del": nil, "code": nil, "pre": nil,
+	"ul": nil, "ol": nil, "li": nil,
+	"a": {"href", "title"},
+}}
+
+// dropContentTags are removed together with everything inside them.
+var dropContentTags = []string{"script", "style", "iframe", "object", "embed", "noscript", "template", "textarea", "select", "svg", "math"}
+
+var voidTags = []string{"br", "hr", "img", "input"}
+
+var urlAttrs = []string{"href", "src"}
+
+// SanitizeHTML removes every tag, attribute and URL scheme the policy does not allow.
+// Text is always re-escaped, unknown tags are dropped but their text is kept.
+func SanitizeHTML(input string, policy *SanitizePolicy) string {
+	z := html.NewTokenizer(strings.NewReader(input))
+	var sb strings.Builder
+	var open []string
+	skipDepth := 0
+	skipTag := ""
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// io.EOF or a read error, either way the input is exhausted
+			break
+		}
+
+		token := z.Token()
+
+		if skipDepth > 0 {
+			switch {
+			case tt == html.StartTagToken && token.Data == skipTag:
+				skipDepth++
+			case tt == html.EndTagToken && token.Data == skipTag:
+				skipDepth--
+			}
+			continue
+		}
+
+		switch tt {
+		case html.TextToken:
+			sb.WriteString(html.EscapeString(token.Data))
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if slices.Contains(dropContentTags, token.Data) {
+				if tt == html.StartTagToken {
+					skipTag = token.Data
+					skipDepth = 1
+				}
+				continue
+			}
+
+			allowed, ok := policy.Tags[token.Data]
+			if !ok {
+				continue
+			}
+
+			sb.WriteString("<" + token.Data)
+			for _, attr := range token.Attr {
+				if attr.Namespace != "" || !slices.Contains(allowed, attr.Key) {
+					continue
+				}
+				if slices.Contains(urlAttrs, attr.Key) && !safeURL(attr.Val) {
+					continue
+				}
+				if token.Data == "input" && attr.Key == "type" && attr.Val != "checkbox" {
+					continue
+				}
+				sb.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+			}
+			if token.Data == "a" {
+				sb.WriteString(` rel="nofollow noopener noreferrer"`)
+			}
+			sb.WriteString(">")
+
+			if !slices.Contains(voidTags, token.Data) && tt == html.StartTagToken {
+				open = append(open, token.Data)
+			}
+
+		case html.EndTagToken:
+			// only close tags that were emitted, closing any unclosed children first
+			idx := slices.Index(open, token.Data)
+			for i := len(open) - 1; idx >= 0 && i >= idx; i-- {
+				sb.WriteString("</" + open[i] + ">")
+			}
+			if idx >= 0 {
+				open = open[:idx]
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		sb.WriteString("</" + open[i] + ">")
+	}
+
+	return sb.String()
+}
+
+// safeURL allows relative URLs and the http, https and mailto schemes.
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		policy *SanitizePolicy
+		want   string
+	}{
+		{"allowed tags", "<p><strong>bold</strong> <em>it</em></p>", PostPolicy, "<p><strong>bold</strong> <em>it</em></p>"},
+		{"text is escaped", "<p>1 &lt; 2 &amp; 3</p>", PostPolicy, "<p>1 &lt; 2 &amp; 3</p>"},
+		{"unknown tag keeps text", "<p><span>kept</span></p>", PostPolicy, "<p>kept</p>"},
+		{"script dropped with content", "<p>a<script>alert(1)</script>b</p>", PostPolicy, "<p>ab</p>"},
+		{"nested dropped tags", "<svg><svg></svg>x</svg>y", PostPolicy, "y"},
+		{"style dropped", "<style>p{}</style><p>x</p>", PostPolicy, "<p>x</p>"},
+		{"event handler dropped", `<p onclick="x()">a</p>`, PostPolicy, "<p>a</p>"},
+		{"link gets rel", `<a href="https://example.com" title="t">l</a>`, PostPolicy,
+			`<a href="https://example.com" title="t" rel="nofollow noopener noreferrer">l</a>`},
+		{"javascript link", `<a href="javascript:alert(1)">l</a>`, PostPolicy, `<a rel="nofollow noopener noreferrer">l</a>`},
+		{"entity encoded scheme", `<a href="&#106;avascript:alert(1)">l</a>`, PostPolicy, `<a rel="nofollow noopener noreferrer">l</a>`},
+		{"data image", `<img src="data:image/png;base64,AAAA" alt="a">`, PostPolicy, `<img alt="a">`},
+		{"attribute is escaped", `<img src="/a.png" alt="&quot;><script>">`, PostPolicy, `<img src="/a.png" alt="&#34;&gt;&lt;script&gt;">`},
+		{"checkbox only", `<input type="text" checked=""><input type="checkbox" disabled="">`, PostPolicy,
+			`<input checked=""><input type="checkbox" disabled="">`},
+		{"unclosed tags are closed", "<blockquote><p>quote", PostPolicy, "<blockquote><p>quote</p></blockquote>"},
+		{"stray end tag", "</p>text", PostPolicy, "text"},
+		{"close children first", "<ul><li>a</ul>", PostPolicy, "<ul><li>a</li></ul>"},
+		{"comment policy drops images", `<p><img src="/a.png">x</p>`, CommentPolicy, "<p>x</p>"},
+		{"comment policy drops headings", "<h1>title</h1>", CommentPolicy, "title"},
+		{"html comment dropped", "<!-- hidden --><p>x</p>", PostPolicy, "<p>x</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.in, tt.policy); got != tt.want {
+				t.Errorf("SanitizeHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		safe bool
+	}{
+		{"https://example.com/a?b=c", true},
+		{"http://example.com", true},
+		{"HTTPS://EXAMPLE.COM", true},
+		{"mailto:someone@example.com", true},
+		{"/relative/path", true},
+		{"#anchor", true},
+		{"", true},
+		{"javascript:alert(1)", false},
+		{"  JaVaScRiPt:alert(1)", false},
+		{"java\tscript:alert(1)", false},
+		{"vbscript:msgbox(1)", false},
+		{"data:text/html,<script>", false},
+		{"file:///etc/passwd", false},
+		{"%zz", false},
+	}
+	for _, tt := range tests {
+		if got := safeURL(tt.url); got != tt.safe {
+			t.Errorf("safeURL(%q) = %v, want %v", tt.url, got, tt.safe)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSlugRunes keeps slugs readable in URLs.
+const maxSlugRunes = 80
+
+// transliterations covers Latin letters that do not decompose into a base letter and accents.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'ø': "o", 'œ': "oe", 'đ': "d", 'ł': "l", 'þ': "th", 'ı': "i",
+}
+
+// Slugify turns a title into a URL slug. Accents are stripped from Latin letters,
+// other scripts such as Chinese are kept as they are, and everything that is not
+// a letter, digit or combining mark becomes a single dash. It returns "" when nothing is left.
+func Slugify(title string) string {
+	var b strings.Builder
+	count, dash := 0, false
+	for _, r := range strings.ToLower(norm.NFC.String(title)) {
+		text := string(r)
+		if t, ok := transliterations[r]; ok {
+			text = t
+		} else if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r) {
+			dash = count > 0
+			continue
+		} else if unicode.Is(unicode.Latin, r) {
+			// Only Latin letters lose their accents: in other scripts such as
+			// Japanese kana or Cyrillic the marks change the letter itself.
+			text = stripMarks(text)
+		}
+
+		width := utf8.RuneCountInString(text)
+		if dash {
+			width++
+		}
+		if count+width > maxSlugRunes {
+			break
+		}
+
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(text)
+		count += width
+	}
+	return strings.TrimRight(b.String(), "-")
+}
+
+// stripMarks decomposes s and drops the combining marks, so "é" becomes "e".
+func stripMarks(s string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err != nil {
+		return s
+	}
+	return stripped
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Hello World", "hello-world"},
+		{"  Go: Tips & Tricks!  ", "go-tips-tricks"},
+		{"already-a-slug", "already-a-slug"},
+		{"Crème brûlée", "creme-brulee"},
+		{"Straße Ærø Łódź", "strasse-aero-lodz"},
+		{"ｆｕｌｌ ｗｉｄｔｈ", "full-width"},
+		{"Go 1.22 released", "go-1-22-released"},
+		{"你好 世界", "你好-世界"},
+		{"ブログ 記事", "ブログ-記事"},
+		{"Привет, мир! Чай й", "привет-мир-чай-й"},
+		{"Ελληνικά", "ελληνικά"},
+		{"नमस्ते दुनिया", "नमस्ते-दुनिया"},
+		{"!!!", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Slugify(tt.in); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSlugifyLength(t *testing.T) {
+	got := Slugify(strings.Repeat("word ", 40))
+	if n := utf8.RuneCountInString(got); n > maxSlugRunes {
+		t.Errorf("slug has %d runes, want at most %d", n, maxSlugRunes)
+	}
+	if strings.HasSuffix(got, "-") {
+		t.Errorf("slug %q ends with a dash", got)
+	}
+}
@@ -0,0 +1,27 @@
+package utils
+
+import "unicode/utf8"
+
+// Truncate cuts s to at most n bytes, backing off to the start of a character
+// so a multi-byte character is not split into invalid UTF-8.
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// TruncateStart is Truncate keeping the end of s, e.g. the extension of a file name.
+func TruncateStart(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return s[start:]
+}
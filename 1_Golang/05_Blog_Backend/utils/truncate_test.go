@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in    string
+		n     int
+		want  string
+		start string
+	}{
+		{"hello", 10, "hello", "hello"},
+		{"hello", 5, "hello", "hello"},
+		{"hello", 3, "hel", "llo"},
+		{"héllo", 2, "h", "lo"},
+		{"héllo", 3, "hé", "llo"},
+		{"日本語.txt", 5, "日", ".txt"},
+		{"日本語.txt", 7, "日本", "語.txt"},
+		{"日本語", 1, "", ""},
+		{"", 0, "", ""},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.in, tt.n); got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+		if got := TruncateStart(tt.in, tt.n); got != tt.start || !utf8.ValidString(got) {
+			t.Errorf("TruncateStart(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.start)
+		}
+	}
+}